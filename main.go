@@ -2,14 +2,73 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	ralphred "github.com/kdeal/ralphred/src"
 )
 
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Whether something is piped or redirected to stdin, as opposed to it being
+// a terminal or /dev/null
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
 func main() {
 	cmdPtr := flag.String("command", "commands", "What alfred command is being called")
-	queryPtr := flag.String("query", "query", "Query to send to the command")
+	queryPtr := flag.String("query", "", "Query to send to the command")
+	formatPtr := flag.String(
+		"format",
+		ralphred.DefaultOutputFormat,
+		fmt.Sprintf("Output format, one of: %s", strings.Join(ralphred.OutputFormatNames(), ", ")),
+	)
+	noDaemonPtr := flag.Bool("no-daemon", false, "Always run the command in this process")
+	idleTimeoutPtr := flag.Duration(
+		"idle-timeout",
+		ralphred.DefaultDaemonIdleTimeout,
+		"How long `ralphred serve` waits for a request before exiting",
+	)
 
 	flag.Parse()
 
-	ralphred.Run(*cmdPtr, *queryPtr)
+	switch flag.Arg(0) {
+	case "serve":
+		exitOnError(ralphred.Serve(ralphred.ServeOptions{
+			SocketPath:  ralphred.DaemonSocketPath(),
+			IdleTimeout: *idleTimeoutPtr,
+		}))
+		return
+	case "reload", "stop":
+		exitOnError(ralphred.ControlDaemon(ralphred.DaemonSocketPath(), flag.Arg(0)))
+		return
+	case "":
+	default:
+		exitOnError(fmt.Errorf("Unknown mode \"%s\", expected serve, reload or stop", flag.Arg(0)))
+	}
+
+	opts := ralphred.RunOptions{Format: *formatPtr}
+	if stdinPiped() && ralphred.CommandReadsStdin(*cmdPtr) {
+		opts.Stdin = os.Stdin
+	}
+	if !*noDaemonPtr {
+		ranOnDaemon, err := ralphred.RunOnDaemon(ralphred.DaemonSocketPath(), *cmdPtr, *queryPtr, opts)
+		exitOnError(err)
+		if ranOnDaemon {
+			return
+		}
+	}
+
+	exitOnError(ralphred.RunWithOptions(*cmdPtr, *queryPtr, opts))
 }
@@ -1,17 +1,62 @@
 package ralphred
 
-import (
-	"encoding/json"
-	"fmt"
-	"os"
+// Modifier keys that can be used in AlfredItem.Mods
+const (
+	ModCmd   = "cmd"
+	ModAlt   = "alt"
+	ModCtrl  = "ctrl"
+	ModShift = "shift"
 )
 
+// Values for AlfredItem.Type
+const (
+	ItemTypeDefault       = "default"
+	ItemTypeFile          = "file"
+	ItemTypeFileSkipCheck = "file:skipcheck"
+)
+
+type AlfredIcon struct {
+	// Either "fileicon" or "filetype". When empty Path is an image to display
+	Type string `json:"type,omitempty"`
+	Path string `json:"path"`
+}
+
+type AlfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+// AlfredMod overrides parts of an item when a modifier key is held
+type AlfredMod struct {
+	Valid     *bool             `json:"valid,omitempty"`
+	Arg       []string          `json:"arg,omitempty"`
+	Subtitle  string            `json:"subtitle,omitempty"`
+	Icon      *AlfredIcon       `json:"icon,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// AlfredAction is what Universal Actions get for an item
+type AlfredAction struct {
+	Text []string `json:"text,omitempty"`
+	URL  []string `json:"url,omitempty"`
+	File []string `json:"file,omitempty"`
+	Auto []string `json:"auto,omitempty"`
+}
+
 type AlfredItem struct {
-	UID          string   `json:"uid,omitempty"`
-	Title        string   `json:"title"`
-	Subtitle     string   `json:"subtitle,omitempty"`
-	Arg          []string `json:"arg"`
-	Autocomplete string   `json:"autocomplete"`
+	UID          string               `json:"uid,omitempty"`
+	Title        string               `json:"title"`
+	Subtitle     string               `json:"subtitle,omitempty"`
+	Arg          []string             `json:"arg"`
+	Autocomplete string               `json:"autocomplete"`
+	Valid        *bool                `json:"valid,omitempty"`
+	Type         string               `json:"type,omitempty"`
+	Match        string               `json:"match,omitempty"`
+	Icon         *AlfredIcon          `json:"icon,omitempty"`
+	Text         *AlfredText          `json:"text,omitempty"`
+	QuickLookURL string               `json:"quicklookurl,omitempty"`
+	Mods         map[string]AlfredMod `json:"mods,omitempty"`
+	Action       *AlfredAction        `json:"action,omitempty"`
 }
 
 func alfredItemFromString(str string, set_uid bool) AlfredItem {
@@ -29,49 +74,87 @@ func alfredItemFromString(str string, set_uid bool) AlfredItem {
 	}
 }
 
-func alfredItemFromStringForwarded(str string, set_uid bool) AlfredItem {
-	uid := ""
-	if set_uid {
-		uid = str
-	}
+func boolPtr(value bool) *bool {
+	return &value
+}
 
-	return AlfredItem{
-		UID:          uid,
-		Title:        str,
-		Subtitle:     "",
-		Arg:          []string{fmt.Sprintf("%s ", str)},
-		Autocomplete: str,
-	}
+// The with* helpers return a copy of the item with the field set so they can
+// be chained off of alfredItemFromString
+
+func (item AlfredItem) withSubtitle(subtitle string) AlfredItem {
+	item.Subtitle = subtitle
+	return item
 }
 
-type AlfredResponse struct {
-	Items []AlfredItem `json:"items"`
+func (item AlfredItem) withValid(valid bool) AlfredItem {
+	item.Valid = boolPtr(valid)
+	return item
+}
+
+func (item AlfredItem) withMatch(match string) AlfredItem {
+	item.Match = match
+	return item
+}
+
+func (item AlfredItem) withText(copyText string, largeType string) AlfredItem {
+	item.Text = &AlfredText{Copy: copyText, LargeType: largeType}
+	return item
+}
+
+func (item AlfredItem) withQuickLookURL(url string) AlfredItem {
+	item.QuickLookURL = url
+	return item
 }
 
-func (resp AlfredResponse) Print() {
-	json_data, err := json.Marshal(resp)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error formatting string commands json")
-		return
+func (item AlfredItem) withMod(key string, mod AlfredMod) AlfredItem {
+	mods := make(map[string]AlfredMod, len(item.Mods)+1)
+	for existingKey, existingMod := range item.Mods {
+		mods[existingKey] = existingMod
 	}
-	fmt.Println(string(json_data))
+	mods[key] = mod
+	item.Mods = mods
+	return item
+}
+
+func (item AlfredItem) withAction(action AlfredAction) AlfredItem {
+	item.Action = &action
+	return item
+}
+
+type AlfredCache struct {
+	Seconds     int  `json:"seconds"`
+	LooseReload bool `json:"loosereload,omitempty"`
+}
+
+type AlfredResponse struct {
+	Items     []AlfredItem      `json:"items"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// Seconds between 0.1 and 5.0 after which alfred reruns the query
+	Rerun float64      `json:"rerun,omitempty"`
+	Cache *AlfredCache `json:"cache,omitempty"`
 }
 
 func errorAlfredItems(errMsg string) []AlfredItem {
 	return []AlfredItem{
-		alfredItemFromString(errMsg, false),
+		alfredItemFromString(errMsg, false).withValid(false),
 	}
 }
 
-// Filter alfred items by the search query. This shouldn't be used when there
-// are a large amount of items
+// Filter alfred items by the search query with the best matches first. Items
+// are matched on Match if it's set and Title otherwise. This shouldn't be used
+// when there are a large amount of items
 func filterAlfredItems(items []AlfredItem, searchQuery []string) []AlfredItem {
-	matchedItems := []AlfredItem{}
-	for _, item := range items {
-		if queryMatches(item.Title, searchQuery) {
-			matchedItems = append(matchedItems, item)
+	matchText := func(i int) string {
+		if items[i].Match != "" {
+			return items[i].Match
 		}
+		return items[i].Title
 	}
 
+	ranked := fuzzyRank(len(items), matchText, searchQuery)
+	matchedItems := make([]AlfredItem, len(ranked))
+	for i, index := range ranked {
+		matchedItems[i] = items[index]
+	}
 	return matchedItems
 }
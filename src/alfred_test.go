@@ -0,0 +1,57 @@
+package ralphred
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func assertItemJSON(t *testing.T, item AlfredItem, expected string) {
+	t.Helper()
+	jsonData, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if string(jsonData) != expected {
+		t.Fatalf("Got %s expected %s", jsonData, expected)
+	}
+}
+
+func TestAlfredItemJSON(t *testing.T) {
+	t.Run("Minimal", func(t *testing.T) {
+		assertItemJSON(t, alfredItemFromString("a", false), `{"title":"a","arg":["a"],"autocomplete":"a"}`)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		assertItemJSON(
+			t,
+			alfredItemFromString("a", false).withValid(false),
+			`{"title":"a","arg":["a"],"autocomplete":"a","valid":false}`,
+		)
+	})
+	t.Run("Mods", func(t *testing.T) {
+		item := alfredItemFromString("a", false).
+			withMod(ModCmd, AlfredMod{Arg: []string{"b"}, Subtitle: "Use b"}).
+			withMod(ModAlt, AlfredMod{Valid: boolPtr(false)})
+		assertItemJSON(
+			t,
+			item,
+			`{"title":"a","arg":["a"],"autocomplete":"a","mods":{"alt":{"valid":false},"cmd":{"arg":["b"],"subtitle":"Use b"}}}`,
+		)
+	})
+	t.Run("TextAndQuickLook", func(t *testing.T) {
+		item := alfredItemFromString("a", false).
+			withText("copy", "large").
+			withQuickLookURL("https://example.com")
+		assertItemJSON(
+			t,
+			item,
+			`{"title":"a","arg":["a"],"autocomplete":"a","text":{"copy":"copy","largetype":"large"},"quicklookurl":"https://example.com"}`,
+		)
+	})
+	t.Run("ModsNotShared", func(t *testing.T) {
+		base := alfredItemFromString("a", false).withMod(ModCmd, AlfredMod{Subtitle: "cmd"})
+		base.withMod(ModAlt, AlfredMod{Subtitle: "alt"})
+		if _, exists := base.Mods[ModAlt]; exists {
+			t.Fatal("withMod modified the original item")
+		}
+	})
+}
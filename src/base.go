@@ -0,0 +1,340 @@
+package ralphred
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var number_base_prefixes = map[string]int{
+	"0x": 16,
+	"0b": 2,
+	"0o": 8,
+}
+
+var number_base_names = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hex",
+	36: "base36",
+}
+
+// Integer literals with a base prefix, like 0xff. These shouldn't be split
+// into a number and unit
+var baseLiteralRegex = regexp.MustCompile(`(?i)^-?0(x[0-9a-f_]+|b[01_]+|o[0-7_]+)$`)
+
+func isBaseLiteral(str string) bool {
+	return baseLiteralRegex.MatchString(str)
+}
+
+// Words that mean the number should be shown in other bases, so convert
+// can hand off to base
+var base_target_names = map[string]bool{
+	"hex": true, "bin": true, "binary": true, "oct": true, "octal": true,
+	"dec": true, "decimal": true, "base36": true,
+}
+
+// Read a literal as decimal, a prefixed base or base36 when it has letters
+func parseBaseLiteral(literal string) (int64, int, error) {
+	lower := strings.ToLower(literal)
+	base := 10
+	digits := lower
+	if len(lower) > 2 {
+		if prefixBase, exists := number_base_prefixes[lower[:2]]; exists {
+			base = prefixBase
+			digits = lower[2:]
+		}
+	}
+	if base == 10 && strings.IndexFunc(lower, func(char rune) bool { return char < '0' || char > '9' }) != -1 {
+		base = 36
+	}
+
+	value, err := strconv.ParseUint(strings.ReplaceAll(digits, "_", ""), base, 64)
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) && numErr.Err == strconv.ErrRange {
+		return 0, base, fmt.Errorf("%s is too large for 64 bits", literal)
+	} else if err != nil {
+		return 0, base, fmt.Errorf("Unable to read %s as %s", literal, number_base_names[base])
+	}
+	// Values past the signed range are the same bits as a negative number
+	return int64(value), base, nil
+}
+
+type baseToken struct {
+	Value string
+	// 1-based like the other parse errors
+	Position int
+}
+
+func isBaseLiteralChar(char byte) bool {
+	return char >= '0' && char <= '9' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char == '_'
+}
+
+func tokenizeBaseExpression(expression string) ([]baseToken, error) {
+	tokens := []baseToken{}
+	for i := 0; i < len(expression); {
+		char := expression[i]
+		switch {
+		case char == ' ' || char == '\t':
+			i++
+		case isBaseLiteralChar(char):
+			start := i
+			for i < len(expression) && isBaseLiteralChar(expression[i]) {
+				i++
+			}
+			tokens = append(tokens, baseToken{expression[start:i], start + 1})
+		case strings.HasPrefix(expression[i:], "<<") || strings.HasPrefix(expression[i:], ">>"):
+			tokens = append(tokens, baseToken{expression[i : i+2], i + 1})
+			i += 2
+		case strings.IndexByte("&|^~-()", char) != -1:
+			tokens = append(tokens, baseToken{string(char), i + 1})
+			i++
+		default:
+			return nil, fmt.Errorf("Unexpected %q at character %d", char, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// Recursive descent with C precedence, from loosest to tightest:
+// | then ^ then & then << >> then the unary ~ and -
+type baseParser struct {
+	Tokens   []baseToken
+	Position int
+	// The literals and the bases they were read in, to say how the input was
+	// understood
+	Literals []string
+	Bases    []int
+}
+
+func (parser *baseParser) peek() string {
+	if parser.Position >= len(parser.Tokens) {
+		return ""
+	}
+	return parser.Tokens[parser.Position].Value
+}
+
+func (parser *baseParser) binary(operators []string, next func() (int64, error), apply func(string, int64, int64) (int64, error)) (int64, error) {
+	left, err := next()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator := parser.peek()
+		matched := false
+		for _, candidate := range operators {
+			matched = matched || operator == candidate
+		}
+		if !matched {
+			return left, nil
+		}
+		parser.Position++
+		right, err := next()
+		if err != nil {
+			return 0, err
+		}
+		left, err = apply(operator, left, right)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func bitwise(operator string, left int64, right int64) (int64, error) {
+	switch operator {
+	case "|":
+		return left | right, nil
+	case "^":
+		return left ^ right, nil
+	case "&":
+		return left & right, nil
+	}
+	if right < 0 || right > 63 {
+		return 0, fmt.Errorf("Can't shift by %d, shifts have to be between 0 and 63", right)
+	}
+	if operator == "<<" {
+		return left << right, nil
+	}
+	return left >> right, nil
+}
+
+func (parser *baseParser) or() (int64, error) {
+	return parser.binary([]string{"|"}, parser.xor, bitwise)
+}
+
+func (parser *baseParser) xor() (int64, error) {
+	return parser.binary([]string{"^"}, parser.and, bitwise)
+}
+
+func (parser *baseParser) and() (int64, error) {
+	return parser.binary([]string{"&"}, parser.shift, bitwise)
+}
+
+func (parser *baseParser) shift() (int64, error) {
+	return parser.binary([]string{"<<", ">>"}, parser.unary, bitwise)
+}
+
+func (parser *baseParser) unary() (int64, error) {
+	if parser.Position >= len(parser.Tokens) {
+		return 0, errors.New("Expression ends before a number")
+	}
+	token := parser.Tokens[parser.Position]
+	switch token.Value {
+	case "~", "-":
+		parser.Position++
+		value, err := parser.unary()
+		if err != nil {
+			return 0, err
+		}
+		if token.Value == "~" {
+			return ^value, nil
+		}
+		return -value, nil
+	case "(":
+		parser.Position++
+		value, err := parser.or()
+		if err != nil {
+			return 0, err
+		}
+		if parser.peek() != ")" {
+			return 0, fmt.Errorf("Missing ) for the ( at character %d", token.Position)
+		}
+		parser.Position++
+		return value, nil
+	}
+	if !isBaseLiteralChar(token.Value[0]) {
+		return 0, fmt.Errorf("Expected a number at character %d got %s", token.Position, token.Value)
+	}
+	parser.Position++
+	value, base, err := parseBaseLiteral(token.Value)
+	parser.Literals = append(parser.Literals, token.Value)
+	parser.Bases = append(parser.Bases, base)
+	return value, err
+}
+
+func evaluateBaseExpression(expression string) (int64, baseParser, error) {
+	tokens, err := tokenizeBaseExpression(expression)
+	if err != nil {
+		return 0, baseParser{}, err
+	}
+	if len(tokens) == 0 {
+		return 0, baseParser{}, errors.New("Type a number or expression")
+	}
+	parser := baseParser{Tokens: tokens}
+	value, err := parser.or()
+	if err != nil {
+		return 0, parser, err
+	}
+	if parser.Position < len(tokens) {
+		token := tokens[parser.Position]
+		return 0, parser, fmt.Errorf("Unexpected %s at character %d", token.Value, token.Position)
+	}
+	return value, parser, nil
+}
+
+// Negative values are shown with a sign, the two's complement items show
+// the bits
+func formatInBase(value int64, base int, prefix string) string {
+	sign := ""
+	magnitude := uint64(value)
+	if value < 0 {
+		sign = "-"
+		magnitude = uint64(-value)
+	}
+	return sign + prefix + strconv.FormatUint(magnitude, base)
+}
+
+// Bit widths the value fits in, either as a signed or unsigned number
+func baseWidths(value int64) []int {
+	widths := []int{}
+	for _, width := range []int{8, 16, 32, 64} {
+		if width == 64 || value >= -(1<<(width-1)) && value < 1<<width {
+			widths = append(widths, width)
+		}
+	}
+	return widths
+}
+
+func baseBytes(value int64, width int) (string, string) {
+	buffer := make([]byte, 8)
+	binary.BigEndian.PutUint64(buffer, uint64(value))
+	bigEndian := buffer[8-width/8:]
+	littleEndian := make([]byte, len(bigEndian))
+	for i, b := range bigEndian {
+		littleEndian[len(bigEndian)-1-i] = b
+	}
+	return fmt.Sprintf("% X", bigEndian), fmt.Sprintf("% X", littleEndian)
+}
+
+func baseItem(title string, subtitle string) AlfredItem {
+	item := alfredItemFromString(title, false).withSubtitle(subtitle)
+	item.UID = subtitle
+	return item
+}
+
+func baseCommand(args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return []AlfredItem{
+			alfredItemFromString("base <number or expression>", false).
+				withSubtitle("Numbers can be decimal, 0x hex, 0b binary, 0o octal or base36, combine them with & | ^ << >> ~").
+				withValid(false),
+		}, nil
+	}
+	value, parser, err := evaluateBaseExpression(strings.Join(args, " "))
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+
+	decimalSubtitle := "Decimal"
+	if len(parser.Bases) == 1 && parser.Bases[0] != 10 {
+		decimalSubtitle = fmt.Sprintf("Decimal, read %s as %s", parser.Literals[0], number_base_names[parser.Bases[0]])
+	}
+	items := []AlfredItem{
+		baseItem(strconv.FormatInt(value, 10), decimalSubtitle),
+		baseItem(formatInBase(value, 16, "0x"), "Hex"),
+		baseItem(formatInBase(value, 2, "0b"), "Binary"),
+		baseItem(formatInBase(value, 8, "0o"), "Octal"),
+		baseItem(formatInBase(value, 36, ""), "Base36"),
+	}
+	if value < 0 {
+		items = append(items, baseItem(strconv.FormatUint(uint64(value), 10), "Unsigned 64-bit"))
+	}
+
+	widths := baseWidths(value)
+	for _, width := range widths {
+		bits := uint64(value)
+		if width < 64 {
+			bits &= 1<<width - 1
+		}
+		signed := int64(bits<<(64-width)) >> (64 - width)
+		items = append(items, baseItem(
+			fmt.Sprintf("0x%0*X", width/4, bits),
+			fmt.Sprintf("%d-bit two's complement, 0b%0*b, signed %d", width, width, bits, signed),
+		))
+	}
+	// Bytes are shown at the smallest width that fits
+	bigEndian, littleEndian := baseBytes(value, widths[0])
+	items = append(items,
+		baseItem(bigEndian, fmt.Sprintf("Big endian %s", pluralize(widths[0]/8, "byte"))),
+		baseItem(littleEndian, fmt.Sprintf("Little endian %s", pluralize(widths[0]/8, "byte"))),
+	)
+	return items, nil
+}
+
+// convert hands numbers with a base prefix, or ones being converted to a
+// base like "255 hex", to base. Returns the args for base
+func baseConversionArgs(args []string) ([]string, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	last := len(args) - 1
+	if last > 0 && base_target_names[strings.ToLower(args[last])] {
+		_, _, err := evaluateBaseExpression(strings.Join(args[:last], " "))
+		return args[:last], err == nil
+	}
+	return args, isBaseLiteral(args[0])
+}
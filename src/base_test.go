@@ -0,0 +1,130 @@
+package ralphred
+
+import (
+	"testing"
+)
+
+func assertBaseValue(t *testing.T, expression string, expected int64) {
+	t.Helper()
+	value, _, err := evaluateBaseExpression(expression)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if value != expected {
+		t.Fatalf("Got %d expected %d", value, expected)
+	}
+}
+
+func TestParseBaseLiteral(t *testing.T) {
+	t.Run("Decimal", func(t *testing.T) {
+		assertBaseValue(t, "255", 255)
+	})
+	t.Run("Hex", func(t *testing.T) {
+		assertBaseValue(t, "0xFF", 255)
+	})
+	t.Run("Binary", func(t *testing.T) {
+		assertBaseValue(t, "0b1111_1111", 255)
+	})
+	t.Run("Octal", func(t *testing.T) {
+		assertBaseValue(t, "0o377", 255)
+	})
+	t.Run("Base36", func(t *testing.T) {
+		assertBaseValue(t, "73", 73)
+		assertBaseValue(t, "zz", 1295)
+	})
+	t.Run("AllBitsSet", func(t *testing.T) {
+		assertBaseValue(t, "0xffffffffffffffff", -1)
+	})
+	t.Run("TooLarge", func(t *testing.T) {
+		_, _, err := evaluateBaseExpression("0x1ffffffffffffffff")
+		if err == nil || err.Error() != "0x1ffffffffffffffff is too large for 64 bits" {
+			t.Fatalf("Got %v expected a range error", err)
+		}
+	})
+}
+
+func TestBaseExpressions(t *testing.T) {
+	cases := map[string]int64{
+		"0xf0 | 0x0f":     0xff,
+		"0xff & 0b1010":   0xa,
+		"0xff ^ 0x0f":     0xf0,
+		"1 << 4":          16,
+		"-16 >> 2":        -4,
+		"~0":              -1,
+		"1 | 2 & 3":       3,
+		"(1 | 2) & 1":     1,
+		"0x10 >> 1 << 2":  32,
+		"1 << 2 | 1 << 1": 6,
+	}
+	for expression, expected := range cases {
+		t.Run(expression, func(t *testing.T) {
+			assertBaseValue(t, expression, expected)
+		})
+	}
+}
+
+func TestBaseExpressionErrors(t *testing.T) {
+	cases := map[string]string{
+		"1 +2":    "Unexpected '+' at character 3",
+		"(1 | 2":  "Missing ) for the ( at character 1",
+		"1 <<":    "Expression ends before a number",
+		"1 << 64": "Can't shift by 64, shifts have to be between 0 and 63",
+		"1 2":     "Unexpected 2 at character 3",
+	}
+	for expression, expected := range cases {
+		t.Run(expression, func(t *testing.T) {
+			_, _, err := evaluateBaseExpression(expression)
+			if err == nil || err.Error() != expected {
+				t.Fatalf("Got %v expected %s", err, expected)
+			}
+		})
+	}
+}
+
+func TestBaseCommand(t *testing.T) {
+	items, err := baseCommand([]string{"-2"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	expected := map[string]string{
+		"Hex":             "-0x2",
+		"Unsigned 64-bit": "18446744073709551614",
+		"8-bit two's complement, 0b11111110, signed -2": "0xFE",
+		"Big endian 1 byte":                             "FE",
+	}
+	found := 0
+	for _, item := range items {
+		if title, exists := expected[item.Subtitle]; exists {
+			found++
+			if item.Title != title {
+				t.Fatalf("Got %s expected %s for %s", item.Title, title, item.Subtitle)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Fatalf("Got %d of the expected items", found)
+	}
+
+	items, _ = baseCommand([]string{"0x1234"})
+	last := items[len(items)-2:]
+	if last[0].Title != "12 34" || last[1].Title != "34 12" {
+		t.Fatalf("Got %s and %s expected the bytes in both orders", last[0].Title, last[1].Title)
+	}
+}
+
+func TestConvertToBase(t *testing.T) {
+	items, err := convertCommand([]string{"0xff"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "255" {
+		t.Fatalf("Got %s expected 255", items[0].Title)
+	}
+	items, err = convertCommand([]string{"255", "hex"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[1].Title != "0xff" {
+		t.Fatalf("Got %s expected 0xff", items[1].Title)
+	}
+}
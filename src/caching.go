@@ -9,12 +9,15 @@ import (
 	"net/http/httputil"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// Requests go through this client so tests can replace the transport
+var httpClient = &http.Client{Timeout: 30 * time.Second}
 
 func requestAndCache(url string, cacheFile string) (*http.Response, error) {
-	resp, err := http.Get(url)
+	resp, err := httpClient.Get(url)
 	if err != nil {
 		return resp, err
 	}
@@ -44,16 +47,9 @@ func requestFromCache(cacheFile string) (*http.Response, error) {
 	return http.ReadResponse(cacheDataReader, nil)
 }
 
-func getCacheFile(url string) (string, error) {
+func getCacheFile(cacheDir string, url string) string {
 	cacheKey := hashString(sha1.New(), url)
-
-	homeDir, err := os.UserCacheDir()
-	if err != nil {
-		return "", err
-	}
-
-	cacheFile := filepath.Join(homeDir, "ralphred", cacheKey)
-	return cacheFile, nil
+	return filepath.Join(cacheDir, cacheKey)
 }
 
 func isCached(cacheFile string, ttl int) (bool, error) {
@@ -69,22 +65,55 @@ func isCached(cacheFile string, ttl int) (bool, error) {
 	return int(cacheAge.Seconds()) < ttl, nil
 }
 
-func cachedRequest(url string, ttl int) (*http.Response, error) {
-	cacheFile, err := getCacheFile(url)
-	if err != nil {
-		return nil, err
-	}
-
-	cached, err := isCached(cacheFile, ttl)
+// Responses are kept in the config's cache dir for its cache TTL
+func cachedRequest(env Environment, config *Config, url string) (*http.Response, error) {
+	cacheFile := getCacheFile(config.CacheDir(env), url)
+	cached, err := isCached(cacheFile, config.Cache.TTL)
 	if err != nil {
 		return nil, err
 	}
 
 	if cached {
 		log.Printf("Loading %s from cache", url)
+		env.debugf("Cache file for %s is %s", url, cacheFile)
 		return requestFromCache(cacheFile)
 	} else {
 		log.Printf("Making request for %s and caching it", url)
 		return requestAndCache(url, cacheFile)
 	}
 }
+
+type memoryCacheEntry struct {
+	value    interface{}
+	loadedAt time.Time
+}
+
+// Values parsed from cached requests are kept in memory so a long running
+// process, like the daemon, doesn't need to read and parse them every time
+var memoryCache = struct {
+	sync.Mutex
+	entries map[string]memoryCacheEntry
+}{entries: map[string]memoryCacheEntry{}}
+
+func memoryCached(key string, ttl int, load func() (interface{}, error)) (interface{}, error) {
+	memoryCache.Lock()
+	defer memoryCache.Unlock()
+
+	entry, exists := memoryCache.entries[key]
+	if exists && int(time.Since(entry.loadedAt).Seconds()) < ttl {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	memoryCache.entries[key] = memoryCacheEntry{value: value, loadedAt: time.Now()}
+	return value, nil
+}
+
+func clearMemoryCache() {
+	memoryCache.Lock()
+	defer memoryCache.Unlock()
+	memoryCache.entries = map[string]memoryCacheEntry{}
+}
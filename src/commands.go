@@ -0,0 +1,293 @@
+package ralphred
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Command is something that can be run from alfred with `--command`. Each
+// command is looked up by its name or any of its aliases
+type Command interface {
+	Name() string
+	Aliases() []string
+	Description() string
+	Usage() string
+	Run(req CommandRequest) ([]AlfredItem, error)
+}
+
+// Commands that read stdin implement this to get it. Others are run without
+// stdin, which lets the CLI use the daemon
+type StdinCommand interface {
+	ReadsStdin() bool
+}
+
+// BasicCommand implements Command with plain values so most commands don't
+// need their own type
+type BasicCommand struct {
+	CommandName        string
+	CommandAliases     []string
+	CommandDescription string
+	CommandUsage       string
+	Handler            func(req CommandRequest) ([]AlfredItem, error)
+	UsesStdin          bool
+}
+
+// argsHandler adapts a command that only needs the parsed args
+func argsHandler(handler func(args []string) ([]AlfredItem, error)) func(CommandRequest) ([]AlfredItem, error) {
+	return func(req CommandRequest) ([]AlfredItem, error) {
+		return handler(req.Args)
+	}
+}
+
+// configHandler adapts a command that needs the parsed args and the config
+func configHandler(handler func(config *Config, args []string) ([]AlfredItem, error)) func(CommandRequest) ([]AlfredItem, error) {
+	return func(req CommandRequest) ([]AlfredItem, error) {
+		return handler(req.Config, req.Args)
+	}
+}
+
+func (cmd BasicCommand) Name() string {
+	return cmd.CommandName
+}
+
+func (cmd BasicCommand) Aliases() []string {
+	return cmd.CommandAliases
+}
+
+func (cmd BasicCommand) Description() string {
+	return cmd.CommandDescription
+}
+
+func (cmd BasicCommand) Usage() string {
+	return cmd.CommandUsage
+}
+
+func (cmd BasicCommand) ReadsStdin() bool {
+	return cmd.UsesStdin
+}
+
+func (cmd BasicCommand) Run(req CommandRequest) ([]AlfredItem, error) {
+	return cmd.Handler(req)
+}
+
+type CommandRegistry struct {
+	commands []Command
+	lookup   map[string]Command
+}
+
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: []Command{},
+		lookup:   map[string]Command{},
+	}
+}
+
+// Register adds the command to the registry. It fails if the name or one of
+// the aliases is already taken by another command
+func (registry *CommandRegistry) Register(cmd Command) error {
+	names := append([]string{cmd.Name()}, cmd.Aliases()...)
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("Command names can't be empty")
+		}
+		if _, exists := registry.lookup[name]; exists {
+			return fmt.Errorf("Command \"%s\" is already registered", name)
+		}
+	}
+
+	for _, name := range names {
+		registry.lookup[name] = cmd
+	}
+	registry.commands = append(registry.commands, cmd)
+	return nil
+}
+
+func (registry *CommandRegistry) Lookup(name string) (Command, bool) {
+	cmd, exists := registry.lookup[name]
+	return cmd, exists
+}
+
+// Commands returns every registered command sorted by name
+func (registry *CommandRegistry) Commands() []Command {
+	cmds := make([]Command, len(registry.commands))
+	copy(cmds, registry.commands)
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name() < cmds[j].Name()
+	})
+	return cmds
+}
+
+// Run finds the command by name and runs it with the request. Unknown
+// commands are reported as an error
+func (registry *CommandRegistry) Run(name string, req CommandRequest) ([]AlfredItem, error) {
+	cmd, exists := registry.Lookup(name)
+	if !exists {
+		return []AlfredItem{}, fmt.Errorf("Unknown command \"%s\"", name)
+	}
+	return cmd.Run(req)
+}
+
+var commandRegistry = NewCommandRegistry()
+
+// CommandReadsStdin is whether the named command wants stdin, so it's only
+// attached when needed
+func CommandReadsStdin(name string) bool {
+	cmd, exists := commandRegistry.Lookup(name)
+	if !exists {
+		return false
+	}
+	stdinCmd, ok := cmd.(StdinCommand)
+	return ok && stdinCmd.ReadsStdin()
+}
+
+// RegisterCommand adds a command to the registry used by Run. This is meant
+// to be called from an init function so commands can live outside this file
+func RegisterCommand(cmd Command) {
+	err := commandRegistry.Register(cmd)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func listCommands(args []string) ([]AlfredItem, error) {
+	cmds := commandRegistry.Commands()
+	items := make([]AlfredItem, len(cmds))
+	for i, cmd := range cmds {
+		items[i] = AlfredItem{
+			UID:          cmd.Name(),
+			Title:        cmd.Name(),
+			Subtitle:     cmd.Description(),
+			Arg:          []string{cmd.Name()},
+			Autocomplete: cmd.Name(),
+		}
+	}
+	return filterAlfredItems(items, args), nil
+}
+
+var builtinCommands = []BasicCommand{
+	{
+		CommandName:        "commands",
+		CommandDescription: "List all of the available commands",
+		CommandUsage:       "commands [search]",
+		Handler:            argsHandler(listCommands),
+	},
+	{
+		CommandName:        "strings",
+		CommandAliases:     []string{"string"},
+		CommandDescription: "Convert or inspect a string",
+		CommandUsage:       "strings <subcommand> [| subcommand...] <string>",
+		Handler: func(req CommandRequest) ([]AlfredItem, error) {
+			// Keep the whitespace in the string being converted
+			return stringCommand(req.Config, req.ArgsWithRawRest(1))
+		},
+	},
+	{
+		CommandName:        "case",
+		CommandDescription: "Convert an identifier between camelCase, snake_case and other styles",
+		CommandUsage:       "case [style] <identifier>",
+		Handler:            argsHandler(caseCommand),
+	},
+	{
+		CommandName:        "convert",
+		CommandDescription: "Convert a measurement between units",
+		CommandUsage:       "convert <number> <unit> <unit>",
+		Handler:            argsHandler(convertCommand),
+	},
+	{
+		CommandName:        "base",
+		CommandDescription: "Show a number in every base and evaluate bitwise expressions",
+		CommandUsage:       "base <number or expression>",
+		Handler:            argsHandler(baseCommand),
+	},
+	{
+		CommandName:        "module",
+		CommandAliases:     []string{"mod"},
+		CommandDescription: "Convert between file paths and module paths for Python, Go, Java, Rust and JS",
+		CommandUsage:       "module [language] <path or module> [from <file>]",
+		Handler:            configHandler(moduleCommand),
+	},
+	{
+		CommandName:        "escape",
+		CommandDescription: "Escape or unescape text for shells, code, JSON, regex, SQL, XML and CSV",
+		CommandUsage:       "escape [[un]target] <text>",
+		Handler:            escapeCommand,
+	},
+	{
+		CommandName:        "json",
+		CommandDescription: "Validate, pretty print, minify or query JSON",
+		CommandUsage:       "json [subcommand] <json>",
+		Handler:            jsonCommand,
+		UsesStdin:          true,
+	},
+	{
+		CommandName:        "hash",
+		CommandDescription: "Hash text or a file, HMAC with a key or check a checksum",
+		CommandUsage:       "hash [file|hmac <key>|verify <checksum> [hmac <key>]] <text>",
+		Handler:            hashCommand,
+	},
+	{
+		CommandName:        "id",
+		CommandDescription: "Generate UUIDs, ULIDs and other IDs, or decode an existing one",
+		CommandUsage:       "id [generator [size] | id [snowflake epoch]]",
+		Handler:            configHandler(idCommand),
+	},
+	{
+		CommandName:        "password",
+		CommandAliases:     []string{"pw"},
+		CommandDescription: "Generate random passwords or diceware style passphrases",
+		CommandUsage:       "password [length] [options...] | password phrase [words] [options...]",
+		Handler:            argsHandler(passwordCommand),
+	},
+	{
+		CommandName:        "jwt",
+		CommandDescription: "Decode a JSON Web Token and optionally verify its signature",
+		CommandUsage:       "jwt <token> [exp|nbf|iat], jwt verify <token> <secret|public key|@key path>",
+		Handler:            jwtCommand,
+	},
+	{
+		CommandName:        "url",
+		CommandDescription: "Split a URL into its parts, edit its parameters or normalize it",
+		CommandUsage:       "url [subcommand] <url> [args...]",
+		Handler:            argsHandler(urlCommand),
+	},
+	{
+		CommandName:        "regex",
+		CommandDescription: "Test a regex against text and preview replacements",
+		CommandUsage:       "regex [-ims] [replace] <pattern> [replacement] <text>",
+		Handler:            regexCommand,
+	},
+	{
+		CommandName:        "unicode",
+		CommandDescription: "Show the code points in text and flag hidden or lookalike characters",
+		CommandUsage:       "unicode <text>",
+		Handler: func(req CommandRequest) ([]AlfredItem, error) {
+			return unicodeCommand(req.ArgsWithRawRest(0))
+		},
+	},
+	{
+		CommandName:        "datetimemath",
+		CommandAliases:     []string{"time"},
+		CommandDescription: "Parse a time, adjust it and show it in different formats",
+		CommandUsage:       "datetimemath <time> [operation...]",
+		Handler:            configHandler(dateTimeMathCommand),
+	},
+	{
+		CommandName:        "devdocs",
+		CommandDescription: "Search the doc sets available on devdocs.io",
+		CommandUsage:       "devdocs [search]",
+		Handler:            devdocsCommand,
+	},
+	{
+		CommandName:        "devdocs_docset",
+		CommandAliases:     []string{"docset"},
+		CommandDescription: "Search the entries in a devdocs.io doc set",
+		CommandUsage:       "devdocs_docset <doc set> [search]",
+		Handler:            devdocsDocSetCommand,
+	},
+}
+
+func init() {
+	for _, cmd := range builtinCommands {
+		RegisterCommand(cmd)
+	}
+}
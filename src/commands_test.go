@@ -0,0 +1,75 @@
+package ralphred
+
+import "testing"
+
+func testCommand(name string, aliases ...string) BasicCommand {
+	return BasicCommand{
+		CommandName:    name,
+		CommandAliases: aliases,
+		Handler: func(req CommandRequest) ([]AlfredItem, error) {
+			return []AlfredItem{alfredItemFromString(name, false)}, nil
+		},
+	}
+}
+
+func TestCommandRegistry(t *testing.T) {
+	t.Run("LookupByAlias", func(t *testing.T) {
+		registry := NewCommandRegistry()
+		if err := registry.Register(testCommand("first", "1st")); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		items, err := registry.Run("1st", newCommandRequest(""))
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != "first" {
+			t.Fatalf("Got %s expected first", items[0].Title)
+		}
+	})
+	t.Run("DuplicateName", func(t *testing.T) {
+		registry := NewCommandRegistry()
+		registry.Register(testCommand("first"))
+		if err := registry.Register(testCommand("second", "first")); err == nil {
+			t.Fatal("Expected error, but didn't get one")
+		}
+		if _, exists := registry.Lookup("second"); exists {
+			t.Fatal("Command was partially registered")
+		}
+	})
+	t.Run("UnknownCommand", func(t *testing.T) {
+		registry := NewCommandRegistry()
+		if _, err := registry.Run("missing", newCommandRequest("")); err == nil {
+			t.Fatal("Expected error, but didn't get one")
+		}
+	})
+	t.Run("SortedCommands", func(t *testing.T) {
+		registry := NewCommandRegistry()
+		registry.Register(testCommand("b"))
+		registry.Register(testCommand("a"))
+		cmds := registry.Commands()
+		if cmds[0].Name() != "a" || cmds[1].Name() != "b" {
+			t.Fatalf("Commands weren't sorted: %s, %s", cmds[0].Name(), cmds[1].Name())
+		}
+	})
+}
+
+func TestListCommands(t *testing.T) {
+	items, err := listCommands([]string{})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if len(items) != len(commandRegistry.Commands()) {
+		t.Fatalf("Got %d items expected %d", len(items), len(commandRegistry.Commands()))
+	}
+}
+
+func TestCommandReadsStdin(t *testing.T) {
+	if !CommandReadsStdin("json") {
+		t.Fatal("Expected json to read stdin")
+	}
+	for _, name := range []string{"strings", "missing"} {
+		if CommandReadsStdin(name) {
+			t.Fatalf("Expected %s not to read stdin", name)
+		}
+	}
+}
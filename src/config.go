@@ -0,0 +1,382 @@
+package ralphred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CacheConfig struct {
+	// Where responses are cached, defaults to Environment.CacheDir
+	Dir string `json:"dir"`
+	// Seconds a cached response is used for
+	TTL int `json:"ttl"`
+}
+
+type DevdocsConfig struct {
+	BaseURL    string `json:"base_url"`
+	MaxResults int    `json:"max_results"`
+}
+
+type DateTimeMathConfig struct {
+	// Merged into output_time_formats, an empty layout removes a format
+	OutputFormats map[string]string `json:"output_formats"`
+	// Day that floor/ceil week use as the start of the week
+	WeekStart string `json:"week_start"`
+}
+
+type JSONConfig struct {
+	// Spaces to indent pretty printed JSON with, 0 indents with tabs
+	Indent int `json:"indent"`
+}
+
+type ModulesConfig struct {
+	// Merged into default_source_roots, keyed by language. An empty list
+	// removes the default roots
+	SourceRoots map[string][]string `json:"source_roots"`
+	// Go module path relative paths are in, the module line of go.mod
+	ModulePrefix string `json:"module_prefix"`
+}
+
+type Config struct {
+	Cache        CacheConfig        `json:"cache"`
+	Devdocs      DevdocsConfig      `json:"devdocs"`
+	DateTimeMath DateTimeMathConfig `json:"datetimemath"`
+	JSON         JSONConfig         `json:"json"`
+	Modules      ModulesConfig      `json:"modules"`
+	// Settings for commands registered outside of this package, keyed by
+	// command name. See CommandSettings
+	Commands map[string]json.RawMessage `json:"commands"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Cache: CacheConfig{
+			TTL: CACHE_TTL,
+		},
+		Devdocs: DevdocsConfig{
+			BaseURL:    DevdocsBaseUrl,
+			MaxResults: 25,
+		},
+		DateTimeMath: DateTimeMathConfig{
+			WeekStart: "Sunday",
+		},
+		JSON: JSONConfig{
+			Indent: 2,
+		},
+		Commands: map[string]json.RawMessage{},
+	}
+}
+
+// CommandSettings decodes the settings for a command into target. Nothing is
+// changed if the config doesn't have settings for the command
+func (config *Config) CommandSettings(command string, target interface{}) error {
+	raw, exists := config.Commands[command]
+	if !exists {
+		return nil
+	}
+	err := json.Unmarshal(raw, target)
+	if err != nil {
+		return fmt.Errorf("commands.%s: %s", command, err)
+	}
+	return nil
+}
+
+func (config *Config) WeekStartDay() time.Weekday {
+	weekday, exists := daysOfWeek[strings.Title(strings.ToLower(config.DateTimeMath.WeekStart))]
+	if !exists {
+		return time.Sunday
+	}
+	return weekday
+}
+
+func (config *Config) OutputTimeFormats() map[string]string {
+	formats := make(map[string]string, len(output_time_formats))
+	for name, layout := range output_time_formats {
+		formats[name] = layout
+	}
+	for name, layout := range config.DateTimeMath.OutputFormats {
+		if layout == "" {
+			delete(formats, name)
+		} else {
+			formats[name] = layout
+		}
+	}
+	return formats
+}
+
+// CacheDir is cache.dir if it's set and the environment's cache dir if not
+func (config *Config) CacheDir(env Environment) string {
+	if config.Cache.Dir != "" {
+		return config.Cache.Dir
+	}
+	return env.CacheDir
+}
+
+func (config *Config) validate() error {
+	problems := []string{}
+	if config.Cache.TTL < 0 {
+		problems = append(problems, "cache.ttl can't be negative")
+	}
+	if config.Cache.Dir != "" && !filepath.IsAbs(config.Cache.Dir) {
+		problems = append(problems, "cache.dir must be an absolute path")
+	}
+
+	baseURL, err := url.Parse(config.Devdocs.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		problems = append(problems, fmt.Sprintf("devdocs.base_url \"%s\" isn't an http(s) URL", config.Devdocs.BaseURL))
+	}
+	if config.Devdocs.MaxResults <= 0 {
+		problems = append(problems, "devdocs.max_results must be greater than 0")
+	}
+
+	if _, exists := daysOfWeek[strings.Title(strings.ToLower(config.DateTimeMath.WeekStart))]; !exists {
+		problems = append(problems, fmt.Sprintf("datetimemath.week_start \"%s\" isn't a day of the week", config.DateTimeMath.WeekStart))
+	}
+	for name := range config.DateTimeMath.OutputFormats {
+		if strings.TrimSpace(name) == "" {
+			problems = append(problems, "datetimemath.output_formats can't have an empty name")
+		}
+	}
+
+	if config.JSON.Indent < 0 || config.JSON.Indent > maxJSONIndent {
+		problems = append(problems, fmt.Sprintf("json.indent must be between 0 and %d", maxJSONIndent))
+	}
+
+	for language, roots := range config.Modules.SourceRoots {
+		if found, exists := findModuleLanguage(language); !exists || found.Name != language {
+			problems = append(problems, fmt.Sprintf("modules.source_roots has an unknown language \"%s\"", language))
+		}
+		for _, root := range roots {
+			if filepath.IsAbs(root) {
+				problems = append(problems, fmt.Sprintf("modules.source_roots.%s must be relative paths", language))
+				break
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+type configEnvOverride struct {
+	Name  string
+	Apply func(*Config, string) error
+}
+
+func intOverride(target func(*Config) *int) func(*Config, string) error {
+	return func(config *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("\"%s\" isn't a whole number", value)
+		}
+		*target(config) = parsed
+		return nil
+	}
+}
+
+func stringOverride(target func(*Config) *string) func(*Config, string) error {
+	return func(config *Config, value string) error {
+		*target(config) = value
+		return nil
+	}
+}
+
+// Environment variables that override settings from the config file. Empty
+// variables are ignored
+var config_env_overrides = []configEnvOverride{
+	{
+		Name:  "RALPHRED_CACHE_DIR",
+		Apply: stringOverride(func(config *Config) *string { return &config.Cache.Dir }),
+	},
+	{
+		Name:  "RALPHRED_CACHE_TTL",
+		Apply: intOverride(func(config *Config) *int { return &config.Cache.TTL }),
+	},
+	{
+		Name:  "RALPHRED_DEVDOCS_BASE_URL",
+		Apply: stringOverride(func(config *Config) *string { return &config.Devdocs.BaseURL }),
+	},
+	{
+		Name:  "RALPHRED_DEVDOCS_MAX_RESULTS",
+		Apply: intOverride(func(config *Config) *int { return &config.Devdocs.MaxResults }),
+	},
+	{
+		Name:  "RALPHRED_WEEK_START",
+		Apply: stringOverride(func(config *Config) *string { return &config.DateTimeMath.WeekStart }),
+	},
+	{
+		Name:  "RALPHRED_JSON_INDENT",
+		Apply: intOverride(func(config *Config) *int { return &config.JSON.Indent }),
+	},
+	{
+		Name:  "RALPHRED_MODULE_PREFIX",
+		Apply: stringOverride(func(config *Config) *string { return &config.Modules.ModulePrefix }),
+	},
+}
+
+// Names the config file is looked up with in the config dir, the first one
+// that exists is used
+var config_file_names = []string{"config.toml", "config.json"}
+
+// Path of the config file. RALPHRED_CONFIG takes priority over the files in
+// the environment's config dir
+func configPath(env Environment, getenv func(string) string) string {
+	if path := getenv("RALPHRED_CONFIG"); path != "" {
+		return path
+	}
+	for _, name := range config_file_names {
+		path := filepath.Join(env.ConfigDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(env.ConfigDir, config_file_names[len(config_file_names)-1])
+}
+
+// Turn a byte offset from a json error into a line and column
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// Config files ending in .toml are TOML, anything else is JSON
+func parseConfig(path string, data []byte) (*Config, error) {
+	if filepath.Ext(path) != ".toml" {
+		return decodeConfig(data, true)
+	}
+	values, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	// The TOML is decoded through JSON so both formats have the same fields
+	// and checks
+	data, err = json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(data, false)
+}
+
+// Decode a JSON config. Errors only have a position when it's from the file
+func decodeConfig(data []byte, withPosition bool) (*Config, error) {
+	config := defaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(config)
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		line, column := lineAndColumn(data, syntaxErr.Offset)
+		return nil, fmt.Errorf("line %d column %d: %s", line, column, syntaxErr)
+	} else if errors.As(err, &typeErr) && withPosition {
+		line, column := lineAndColumn(data, typeErr.Offset)
+		return nil, fmt.Errorf("line %d column %d: %s should be %s", line, column, typeErr.Field, typeErr.Type)
+	} else if errors.As(err, &typeErr) {
+		return nil, fmt.Errorf("%s should be %s", typeErr.Field, typeErr.Type)
+	} else if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+type cachedConfigFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+	config  *Config
+}
+
+// The parsed config file is kept until the file changes so the daemon doesn't
+// need to parse it on every request
+var configFileCache = struct {
+	sync.Mutex
+	file *cachedConfigFile
+}{}
+
+func clearConfigCache() {
+	configFileCache.Lock()
+	defer configFileCache.Unlock()
+	configFileCache.file = nil
+}
+
+func readConfigFile(path string) (*Config, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return defaultConfig(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	configFileCache.Lock()
+	defer configFileCache.Unlock()
+	cached := configFileCache.file
+	if cached != nil && cached.path == path && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	configFileCache.file = &cachedConfigFile{
+		path:    path,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		config:  config,
+	}
+	return config, nil
+}
+
+// Load the config file and apply the environment overrides. A missing config
+// file isn't an error, the defaults are used instead
+func loadConfig(env Environment, getenv func(string) string) (*Config, error) {
+	path := configPath(env, getenv)
+	env.debugf("Loading config from %s", path)
+
+	fileConfig, err := readConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	// Copy so the overrides don't end up in the cached config
+	config := *fileConfig
+
+	for _, override := range config_env_overrides {
+		value := getenv(override.Name)
+		if value == "" {
+			continue
+		}
+		err := override.Apply(&config, value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s: %s", override.Name, err)
+		}
+	}
+
+	err = config.validate()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,196 @@
+package ralphred
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadTestConfig(t *testing.T, contents string, env map[string]string) (*Config, error) {
+	t.Helper()
+	return loadTestConfigFile(t, "config.json", contents, env)
+}
+
+func loadTestConfigFile(t *testing.T, name string, contents string, env map[string]string) (*Config, error) {
+	t.Helper()
+	clearConfigCache()
+	path := filepath.Join(t.TempDir(), name)
+	if contents != "" {
+		err := os.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+	}
+	getenv := func(name string) string {
+		if name == "RALPHRED_CONFIG" {
+			return path
+		}
+		return env[name]
+	}
+	return loadConfig(Environment{}, getenv)
+}
+
+func assertConfigError(t *testing.T, contents string, env map[string]string, expected string) {
+	t.Helper()
+	_, err := loadTestConfig(t, contents, env)
+	if err == nil {
+		t.Fatalf("Expected error, but didn't get one")
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Got error %q expected it to contain %q", err, expected)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("MissingFileUsesDefaults", func(t *testing.T) {
+		config, err := loadTestConfig(t, "", nil)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if config.Devdocs.MaxResults != 25 || config.Cache.TTL != CACHE_TTL {
+			t.Fatalf("Didn't get the defaults: %+v", config)
+		}
+	})
+	t.Run("FileOverridesDefaults", func(t *testing.T) {
+		config, err := loadTestConfig(t, `{"devdocs": {"max_results": 5}}`, nil)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if config.Devdocs.MaxResults != 5 || config.Devdocs.BaseURL != DevdocsBaseUrl {
+			t.Fatalf("Config wasn't merged with defaults: %+v", config.Devdocs)
+		}
+	})
+	t.Run("EnvOverridesFile", func(t *testing.T) {
+		config, err := loadTestConfig(
+			t,
+			`{"cache": {"ttl": 5}}`,
+			map[string]string{"RALPHRED_CACHE_TTL": "10", "RALPHRED_WEEK_START": "monday"},
+		)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if config.Cache.TTL != 10 || config.WeekStartDay() != time.Monday {
+			t.Fatalf("Env wasn't applied: %+v", config)
+		}
+	})
+	t.Run("SyntaxError", func(t *testing.T) {
+		assertConfigError(t, "{\n  \"cache\": {\"ttl\": 5,}\n}", nil, "line 2 column")
+	})
+	t.Run("WrongType", func(t *testing.T) {
+		assertConfigError(t, `{"cache": {"ttl": "long"}}`, nil, "line 1 column")
+	})
+	t.Run("UnknownField", func(t *testing.T) {
+		assertConfigError(t, `{"devdocs": {"max": 5}}`, nil, `unknown field "max"`)
+	})
+	t.Run("InvalidValues", func(t *testing.T) {
+		assertConfigError(t, `{"devdocs": {"max_results": 0}}`, nil, "devdocs.max_results must be greater than 0")
+		assertConfigError(t, `{"devdocs": {"base_url": "devdocs.io"}}`, nil, "devdocs.base_url")
+		assertConfigError(t, `{"datetimemath": {"week_start": "someday"}}`, nil, "datetimemath.week_start")
+		assertConfigError(t, `{"json": {"indent": -1}}`, nil, "json.indent must be between 0 and 16")
+	})
+	t.Run("InvalidEnv", func(t *testing.T) {
+		assertConfigError(t, "", map[string]string{"RALPHRED_DEVDOCS_MAX_RESULTS": "lots"}, "RALPHRED_DEVDOCS_MAX_RESULTS")
+	})
+	t.Run("CommandSettings", func(t *testing.T) {
+		config, err := loadTestConfig(t, `{"commands": {"custom": {"greeting": "hi"}}}`, nil)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		var custom struct {
+			Greeting string `json:"greeting"`
+		}
+		err = config.CommandSettings("custom", &custom)
+		if err != nil || custom.Greeting != "hi" {
+			t.Fatalf("Got %q, %s", custom.Greeting, err)
+		}
+	})
+}
+
+func TestLoadTOMLConfig(t *testing.T) {
+	t.Run("Tables", func(t *testing.T) {
+		contents := `# Slower devdocs
+[devdocs]
+max_results = 5
+
+[commands.custom]
+greeting = "hi"
+`
+		config, err := loadTestConfigFile(t, "config.toml", contents, nil)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if config.Devdocs.MaxResults != 5 || config.Devdocs.BaseURL != DevdocsBaseUrl {
+			t.Fatalf("Config wasn't merged with defaults: %+v", config.Devdocs)
+		}
+		var custom struct {
+			Greeting string `json:"greeting"`
+		}
+		err = config.CommandSettings("custom", &custom)
+		if err != nil || custom.Greeting != "hi" {
+			t.Fatalf("Got %q, %s", custom.Greeting, err)
+		}
+	})
+	t.Run("SyntaxError", func(t *testing.T) {
+		assertTOMLConfigError(t, "[cache]\nttl = \"long", "line 2: unterminated string")
+	})
+	t.Run("WrongType", func(t *testing.T) {
+		assertTOMLConfigError(t, "cache.ttl = \"long\"", "cache.ttl should be int")
+	})
+	t.Run("UnknownField", func(t *testing.T) {
+		assertTOMLConfigError(t, "devdocs = {max = 5}", `unknown field "max"`)
+	})
+	t.Run("InvalidValues", func(t *testing.T) {
+		assertTOMLConfigError(t, "json.indent = -1", "json.indent must be between 0 and 16")
+	})
+}
+
+func assertTOMLConfigError(t *testing.T, contents string, expected string) {
+	t.Helper()
+	_, err := loadTestConfigFile(t, "config.toml", contents, nil)
+	if err == nil {
+		t.Fatalf("Expected error, but didn't get one")
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Got error %q expected it to contain %q", err, expected)
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	env := Environment{ConfigDir: t.TempDir()}
+	getenv := func(string) string { return "" }
+	t.Run("DefaultsToJSON", func(t *testing.T) {
+		path := configPath(env, getenv)
+		if filepath.Base(path) != "config.json" {
+			t.Fatalf("Got %s expected config.json", path)
+		}
+	})
+	t.Run("PrefersTOML", func(t *testing.T) {
+		for _, name := range []string{"config.json", "config.toml"} {
+			err := os.WriteFile(filepath.Join(env.ConfigDir, name), []byte{}, 0600)
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+		}
+		path := configPath(env, getenv)
+		if filepath.Base(path) != "config.toml" {
+			t.Fatalf("Got %s expected config.toml", path)
+		}
+	})
+}
+
+func TestOutputTimeFormats(t *testing.T) {
+	config := defaultConfig()
+	config.DateTimeMath.OutputFormats = map[string]string{"Year": "2006", "Kitchen": ""}
+	formats := config.OutputTimeFormats()
+	if formats["Year"] != "2006" {
+		t.Fatal("Format wasn't added")
+	}
+	if _, exists := formats["Kitchen"]; exists {
+		t.Fatal("Format wasn't removed")
+	}
+	if _, exists := output_time_formats["Year"]; exists {
+		t.Fatal("Defaults were modified")
+	}
+}
@@ -12,6 +12,10 @@ func convertCommand(args []string) ([]AlfredItem, error) {
 		return []AlfredItem{}, errors.New("Type measurement with unit to start converting")
 	}
 
+	if baseArgs, isBase := baseConversionArgs(args); isBase {
+		return baseCommand(baseArgs)
+	}
+
 	args = splitUnitFromNumber(args)
 
 	measurement, err := strconv.ParseFloat(args[0], 64)
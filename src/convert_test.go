@@ -220,3 +220,7 @@ func TestDigitalPrefixes(t *testing.T) {
 		assertResponse(t, []string{"6.7", "Kib", "b"}, "6860.8b")
 	})
 }
+
+func TestQuotedMeasurement(t *testing.T) {
+	assertResponse(t, []string{"2 c", "f"}, "35.6f")
+}
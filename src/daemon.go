@@ -0,0 +1,390 @@
+package ralphred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const DefaultDaemonIdleTimeout = 15 * time.Minute
+
+// How long the CLI waits to connect before running the command itself
+const daemonDialTimeout = 100 * time.Millisecond
+
+// Fetching a doc set the first time can take a while, so this is generous
+const daemonRequestTimeout = 30 * time.Second
+
+// Version is set when building with
+// -ldflags "-X github.com/kdeal/ralphred/src.Version=..."
+var Version = "dev"
+
+// The executable's size and modification time change with every rebuild,
+// even when Version doesn't, so they're part of the build's identity too
+func buildVersion() string {
+	path, err := os.Executable()
+	if err != nil {
+		return Version
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Version
+	}
+	return fmt.Sprintf("%s %d %d", Version, info.Size(), info.ModTime().UnixNano())
+}
+
+// Worked out at startup, a daemon's executable can be replaced while it runs
+var daemonVersion = buildVersion()
+
+const (
+	daemonOpRun    = "run"
+	daemonOpReload = "reload"
+	daemonOpStop   = "stop"
+)
+
+type daemonRequest struct {
+	Op string `json:"op"`
+	// Build of the CLI, run requests are refused by a daemon from another
+	// build since its results could differ
+	Version string `json:"version,omitempty"`
+	Command string `json:"command,omitempty"`
+	Query   string `json:"query,omitempty"`
+	Format  string `json:"format,omitempty"`
+	// Variables from the CLI's environment that start with one of
+	// environmentVarPrefixes, and processEnvironmentVars
+	Env map[string]string `json:"env,omitempty"`
+}
+
+type daemonResponse struct {
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Set when the daemon can't run the command the same as the CLI would,
+	// so the CLI should run it itself
+	Fallback string `json:"fallback,omitempty"`
+}
+
+type ServeOptions struct {
+	SocketPath string
+	// The daemon exits after this long without a request
+	IdleTimeout time.Duration
+}
+
+// The socket is keyed on the workflow's bundle id rather than kept in the
+// cache dir. Alfred sets alfred_workflow_cache but a terminal doesn't, so
+// they would look in different places. To share a daemon started from a
+// terminal with Alfred, run it with alfred_workflow_bundleid set
+func daemonSocketPath(getenv func(string) string) string {
+	name := "ralphred"
+	if bundleID := getenv("alfred_workflow_bundleid"); bundleID != "" {
+		name = filepath.Base(bundleID)
+	}
+	dir := fmt.Sprintf("ralphred-%d", os.Getuid())
+	return filepath.Join(os.TempDir(), dir, name+".sock")
+}
+
+func DaemonSocketPath() string {
+	return daemonSocketPath(os.Getenv)
+}
+
+func daemonEnv() map[string]string {
+	env := map[string]string{}
+	for _, variable := range os.Environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		for _, prefix := range environmentVarPrefixes {
+			if strings.HasPrefix(parts[0], prefix) && len(parts) == 2 {
+				env[parts[0]] = parts[1]
+			}
+		}
+	}
+	for _, name := range processEnvironmentVars {
+		if value, exists := os.LookupEnv(name); exists {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+type daemon struct {
+	listener   net.Listener
+	socketPath string
+	version    string
+	// The daemon's own values of processEnvironmentVars
+	processEnv  map[string]string
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+
+	connections sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+func newDaemon(opts ServeOptions) (*daemon, error) {
+	err := os.MkdirAll(filepath.Dir(opts.SocketPath), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	// A socket left behind by a daemon that didn't shut down cleanly will
+	// refuse connections, so it's safe to remove
+	if _, err := os.Stat(opts.SocketPath); err == nil {
+		conn, err := net.DialTimeout("unix", opts.SocketPath, daemonDialTimeout)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("A daemon is already listening on %s", opts.SocketPath)
+		}
+		os.Remove(opts.SocketPath)
+	}
+
+	listener, err := net.Listen("unix", opts.SocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultDaemonIdleTimeout
+	}
+
+	d := &daemon{
+		listener:    listener,
+		socketPath:  opts.SocketPath,
+		version:     daemonVersion,
+		processEnv:  map[string]string{},
+		idleTimeout: idleTimeout,
+	}
+	for _, name := range processEnvironmentVars {
+		if value, exists := os.LookupEnv(name); exists {
+			d.processEnv[name] = value
+		}
+	}
+	d.idleTimer = time.AfterFunc(idleTimeout, func() {
+		log.Printf("No requests for %s, shutting down", idleTimeout)
+		d.shutdown()
+	})
+	return d, nil
+}
+
+// Stop accepting connections. Requests that are already being handled are
+// allowed to finish before serve returns
+func (d *daemon) shutdown() {
+	d.closeOnce.Do(func() {
+		d.idleTimer.Stop()
+		d.listener.Close()
+	})
+}
+
+// Drop everything kept in memory so it's loaded again on the next request
+func (d *daemon) reload() {
+	clearMemoryCache()
+	clearConfigCache()
+	log.Println("Reloaded daemon")
+}
+
+func (d *daemon) serve() error {
+	defer os.Remove(d.socketPath)
+	log.Printf("Listening on %s", d.socketPath)
+
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				d.connections.Wait()
+				return nil
+			}
+			return err
+		}
+
+		d.idleTimer.Reset(d.idleTimeout)
+		d.connections.Add(1)
+		go func() {
+			defer d.connections.Done()
+			d.handle(conn)
+		}()
+	}
+}
+
+func (d *daemon) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonRequestTimeout))
+
+	var req daemonRequest
+	var resp daemonResponse
+	err := json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		resp.Error = fmt.Sprintf("Invalid request: %s", err)
+	} else {
+		resp = d.respond(req)
+	}
+	resp.Version = d.version
+
+	err = json.NewEncoder(conn).Encode(resp)
+	if err != nil {
+		log.Printf("Failed to send response: %s", err)
+	}
+}
+
+func (d *daemon) respond(req daemonRequest) daemonResponse {
+	switch req.Op {
+	case daemonOpRun:
+		if req.Version != d.version {
+			return daemonResponse{Error: fmt.Sprintf("Daemon is running build \"%s\" not \"%s\"", d.version, req.Version)}
+		}
+		for _, name := range processEnvironmentVars {
+			if req.Env[name] != d.processEnv[name] {
+				return daemonResponse{Fallback: fmt.Sprintf("%s is \"%s\" for the daemon", name, d.processEnv[name])}
+			}
+		}
+		// Commands only use the request's config and environment, so they can
+		// run at the same time
+		// Only the CLI's environment is used so the command runs the same as
+		// it would have in the CLI
+		getenv := func(name string) string {
+			return req.Env[name]
+		}
+		var output bytes.Buffer
+		err := RunWithOptions(req.Command, req.Query, RunOptions{
+			Format: req.Format,
+			Output: &output,
+			Getenv: getenv,
+		})
+		if err != nil {
+			return daemonResponse{Error: err.Error()}
+		}
+		return daemonResponse{Output: output.String()}
+	case daemonOpReload:
+		d.reload()
+		return daemonResponse{}
+	case daemonOpStop:
+		d.shutdown()
+		return daemonResponse{}
+	default:
+		return daemonResponse{Error: fmt.Sprintf("Unknown daemon operation \"%s\"", req.Op)}
+	}
+}
+
+// Serve runs the daemon until it's idle for too long or gets SIGINT/SIGTERM.
+// SIGHUP reloads it
+func Serve(opts ServeOptions) error {
+	d, err := newDaemon(opts)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				d.reload()
+			} else {
+				log.Printf("Got %s, shutting down", sig)
+				d.shutdown()
+			}
+		}
+	}()
+
+	return d.serve()
+}
+
+func sendDaemonRequest(socketPath string, req daemonRequest) (daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, daemonDialTimeout)
+	if err != nil {
+		return daemonResponse{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonRequestTimeout))
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return daemonResponse{}, err
+	}
+
+	var resp daemonResponse
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		return daemonResponse{}, err
+	}
+	return resp, nil
+}
+
+// RunOnDaemon sends the command to the daemon and writes its output. It
+// returns false if the daemon couldn't be reached, in which case the command
+// should be run in process instead
+func RunOnDaemon(socketPath string, cmd string, query string, opts RunOptions) (bool, error) {
+	// The daemon can't read the CLI's stdin, so commands given it run in
+	// process
+	if opts.Stdin != nil {
+		return false, nil
+	}
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	// Not having a daemon running is normal, so it's only logged when
+	// debugging
+	logFallback := func(format string, v ...interface{}) {
+		if getenv("alfred_debug") == "1" {
+			log.Printf(format, v...)
+		}
+	}
+
+	resp, err := sendDaemonRequest(socketPath, daemonRequest{
+		Op:      daemonOpRun,
+		Version: daemonVersion,
+		Command: cmd,
+		Query:   query,
+		Format:  opts.Format,
+		Env:     daemonEnv(),
+	})
+	if err != nil {
+		logFallback("Unable to use daemon: %s", err)
+		return false, nil
+	}
+	// A daemon from an older build is stopped so the next run can start a
+	// current one
+	if resp.Version != daemonVersion {
+		log.Printf("Stopping daemon from another build: %s", resp.Error)
+		sendDaemonRequest(socketPath, daemonRequest{Op: daemonOpStop})
+		return false, nil
+	}
+	if resp.Fallback != "" {
+		logFallback("Running in process: %s", resp.Fallback)
+		return false, nil
+	}
+	if resp.Error != "" {
+		return true, errors.New(resp.Error)
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	_, err = io.WriteString(output, resp.Output)
+	return true, err
+}
+
+// ControlDaemon sends a reload or stop to a running daemon
+func ControlDaemon(socketPath string, op string) error {
+	if op != daemonOpReload && op != daemonOpStop {
+		return fmt.Errorf("Unknown daemon operation \"%s\"", op)
+	}
+	resp, err := sendDaemonRequest(socketPath, daemonRequest{Op: op})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package ralphred
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startTestDaemon(t *testing.T) (string, chan error) {
+	t.Helper()
+	// Unix socket paths have a short length limit, so avoid t.TempDir
+	dir, err := os.MkdirTemp("", "ralphred")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "daemon.sock")
+	d, err := newDaemon(ServeOptions{SocketPath: socketPath, IdleTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- d.serve()
+	}()
+	return socketPath, done
+}
+
+func TestDaemon(t *testing.T) {
+	socketPath, done := startTestDaemon(t)
+
+	t.Run("MatchesInProcess", func(t *testing.T) {
+		for _, format := range []string{"alfred", "tsv"} {
+			var daemonOutput, localOutput bytes.Buffer
+			opts := RunOptions{Format: format, Output: &daemonOutput}
+			ranOnDaemon, err := RunOnDaemon(socketPath, "strings", "upper abc", opts)
+			if err != nil || !ranOnDaemon {
+				t.Fatalf("Failed to run on daemon: %t, %s", ranOnDaemon, err)
+			}
+
+			opts.Output = &localOutput
+			err = RunWithOptions("strings", "upper abc", opts)
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if daemonOutput.String() != localOutput.String() {
+				t.Fatalf("Got %q from daemon expected %q", daemonOutput.String(), localOutput.String())
+			}
+		}
+	})
+	t.Run("ErrorsAreReturned", func(t *testing.T) {
+		ranOnDaemon, err := RunOnDaemon(socketPath, "strings", "upper abc", RunOptions{Format: "xml"})
+		if !ranOnDaemon || err == nil {
+			t.Fatalf("Expected the daemon to return an error: %t, %s", ranOnDaemon, err)
+		}
+	})
+	t.Run("Reload", func(t *testing.T) {
+		memoryCached("key", 60, func() (interface{}, error) { return 1, nil })
+		if err := ControlDaemon(socketPath, "reload"); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(memoryCache.entries) != 0 {
+			t.Fatal("Memory cache wasn't cleared on reload")
+		}
+	})
+	t.Run("Stop", func(t *testing.T) {
+		if err := ControlDaemon(socketPath, "stop"); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Daemon didn't stop")
+		}
+		if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+			t.Fatal("Socket wasn't removed")
+		}
+	})
+	t.Run("FallbackWhenNotRunning", func(t *testing.T) {
+		ranOnDaemon, err := RunOnDaemon(socketPath, "strings", "upper abc", RunOptions{})
+		if ranOnDaemon || err != nil {
+			t.Fatalf("Expected to fall back: %t, %s", ranOnDaemon, err)
+		}
+	})
+}
+
+func TestDaemonIdleTimeout(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ralphred")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d, err := newDaemon(ServeOptions{SocketPath: filepath.Join(dir, "daemon.sock"), IdleTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- d.serve()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Daemon didn't stop when idle")
+	}
+}
+
+func TestDaemonFromAnotherBuild(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ralphred")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "daemon.sock")
+	d, err := newDaemon(ServeOptions{SocketPath: socketPath, IdleTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	d.version = "old build"
+	done := make(chan error, 1)
+	go func() {
+		done <- d.serve()
+	}()
+
+	ranOnDaemon, err := RunOnDaemon(socketPath, "strings", "upper abc", RunOptions{})
+	if ranOnDaemon || err != nil {
+		t.Fatalf("Expected to fall back: %t, %s", ranOnDaemon, err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Daemon from another build wasn't stopped")
+	}
+}
+
+func TestDaemonSocketPath(t *testing.T) {
+	alfredPath := daemonSocketPath(func(name string) string {
+		return map[string]string{
+			"alfred_workflow_bundleid": "com.kdeal.ralphred",
+			"alfred_workflow_cache":    "/alfred/cache",
+		}[name]
+	})
+	terminalPath := daemonSocketPath(func(name string) string {
+		return map[string]string{"alfred_workflow_bundleid": "com.kdeal.ralphred"}[name]
+	})
+	if alfredPath != terminalPath {
+		t.Fatalf("Got %s from alfred expected %s", alfredPath, terminalPath)
+	}
+	if filepath.Base(alfredPath) != "com.kdeal.ralphred.sock" {
+		t.Fatalf("Got %s expected the bundle id in the name", alfredPath)
+	}
+}
+
+func TestDaemonProcessEnvironment(t *testing.T) {
+	t.Setenv("TZ", "UTC")
+	socketPath, _ := startTestDaemon(t)
+	defer ControlDaemon(socketPath, "stop")
+
+	ranOnDaemon, err := RunOnDaemon(socketPath, "strings", "upper abc", RunOptions{Output: &bytes.Buffer{}})
+	if err != nil || !ranOnDaemon {
+		t.Fatalf("Failed to run on daemon: %t, %s", ranOnDaemon, err)
+	}
+
+	t.Setenv("TZ", "Asia/Tokyo")
+	ranOnDaemon, err = RunOnDaemon(socketPath, "strings", "upper abc", RunOptions{})
+	if ranOnDaemon || err != nil {
+		t.Fatalf("Expected to fall back when TZ differs: %t, %s", ranOnDaemon, err)
+	}
+}
+
+// The daemon runs requests at the same time, so each has to use its own
+// config
+func TestConcurrentRuns(t *testing.T) {
+	configDir := t.TempDir()
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		indent := 2 + i%2*2
+		go func() {
+			var output bytes.Buffer
+			err := RunWithOptions("strings", "jsonpretty [1]", RunOptions{
+				Format: "tsv",
+				Output: &output,
+				Getenv: func(name string) string {
+					return map[string]string{
+						"XDG_CONFIG_HOME":      configDir,
+						"RALPHRED_JSON_INDENT": fmt.Sprint(indent),
+					}[name]
+				},
+			})
+			// The tsv format puts line breaks back as spaces
+			expected := "[ " + strings.Repeat(" ", indent) + "1 ]\t"
+			if err == nil && !strings.HasPrefix(output.String(), expected) {
+				err = fmt.Errorf("Got %q expected an indent of %d", output.String(), indent)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +15,8 @@ var input_time_formats []string = []string{
 	time.RFC3339,
 	time.RFC1123,
 	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
 	time.UnixDate,
 	time.RFC3339Nano,
 	time.Kitchen,
@@ -51,6 +54,9 @@ var daysOfWeek = map[string]time.Weekday{
 	"Sat":       time.Saturday,
 }
 
+// Current time used for "now" and "utc", replaced in tests
+var now = time.Now
+
 type WeekdayOperation string
 
 const (
@@ -61,9 +67,9 @@ const (
 
 func parseDateTimeFromToken(token string) (time.Time, bool) {
 	if token == "now" {
-		return time.Now(), false
+		return now(), false
 	} else if token == "utc" {
-		return time.Now().UTC(), false
+		return now().UTC(), false
 	}
 	for _, layout := range input_time_formats {
 		time, err := time.Parse(layout, token)
@@ -202,7 +208,12 @@ func addToTime(init_time time.Time, args []string, negate bool) (time.Time, erro
 	}
 }
 
-func floorTime(init_time time.Time, args []string) (time.Time, error) {
+// Number of days since the configured start of the week
+func daysSinceWeekStart(init_time time.Time, config *Config) int {
+	return (int(init_time.Weekday()-config.WeekStartDay()) + 7) % 7
+}
+
+func floorTime(init_time time.Time, args []string, config *Config) (time.Time, error) {
 	if len(args) != 1 {
 		return init_time, fmt.Errorf("floor/start expects 1 argument got: %s", args)
 	}
@@ -243,8 +254,8 @@ func floorTime(init_time time.Time, args []string) (time.Time, error) {
 			init_time.Location(),
 		)
 	case "week":
-		day_floor, _ := floorTime(init_time, []string{"day"})
-		new_time, _ = findWeekday(day_floor, []string{"sunday"}, ThisWeekday)
+		day_floor, _ := floorTime(init_time, []string{"day"}, config)
+		new_time = day_floor.AddDate(0, 0, -daysSinceWeekStart(day_floor, config))
 	case "month":
 		new_time = time.Date(
 			init_time.Year(),
@@ -280,7 +291,7 @@ func daysIn(month time.Month, year int) int {
 	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }
 
-func ceilTime(init_time time.Time, args []string) (time.Time, error) {
+func ceilTime(init_time time.Time, args []string, config *Config) (time.Time, error) {
 	if len(args) != 1 {
 		return init_time, fmt.Errorf("ceil/end expects 1 argument got: %s", args)
 	}
@@ -321,8 +332,8 @@ func ceilTime(init_time time.Time, args []string) (time.Time, error) {
 			init_time.Location(),
 		)
 	case "week":
-		day_floor, _ := ceilTime(init_time, []string{"day"})
-		new_time, _ = findWeekday(day_floor, []string{"saturday"}, ThisWeekday)
+		day_ceil, _ := ceilTime(init_time, []string{"day"}, config)
+		new_time = day_ceil.AddDate(0, 0, 6-daysSinceWeekStart(day_ceil, config))
 	case "month":
 		new_time = time.Date(
 			init_time.Year(),
@@ -353,12 +364,12 @@ func ceilTime(init_time time.Time, args []string) (time.Time, error) {
 
 type TimeOperation struct {
 	Commands []string
-	Apply    func(time.Time, []string) (time.Time, error)
+	Apply    func(time.Time, []string, *Config) (time.Time, error)
 }
 
 var identityOperation = TimeOperation{
 	Commands: []string{},
-	Apply: func(init_time time.Time, args []string) (time.Time, error) {
+	Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 		// This is just used as the initial operation and should be overwritten
 		// by the first token and never have any arguments
 		if len(args) > 0 {
@@ -371,7 +382,7 @@ var identityOperation = TimeOperation{
 var operations = []TimeOperation{
 	{
 		Commands: []string{"to", "in"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			convert_to := strings.Join(args, " ")
 			switch convert_to {
 			case "":
@@ -393,31 +404,31 @@ var operations = []TimeOperation{
 	},
 	{
 		Commands: []string{"next"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			return findWeekday(init_time, args, NextWeekday)
 		},
 	},
 	{
 		Commands: []string{"prev"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			return findWeekday(init_time, args, PrevWeekday)
 		},
 	},
 	{
 		Commands: []string{"this"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			return findWeekday(init_time, args, ThisWeekday)
 		},
 	},
 	{
 		Commands: []string{"+"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			return addToTime(init_time, args, false)
 		},
 	},
 	{
 		Commands: []string{"-"},
-		Apply: func(init_time time.Time, args []string) (time.Time, error) {
+		Apply: func(init_time time.Time, args []string, _ *Config) (time.Time, error) {
 			return addToTime(init_time, args, true)
 		},
 	},
@@ -441,7 +452,7 @@ func makeOperationsMap() map[string]TimeOperation {
 	return operation_map
 }
 
-func adjustTime(init_time time.Time, args []string) (time.Time, error) {
+func adjustTime(init_time time.Time, args []string, config *Config) (time.Time, error) {
 	if len(args) == 0 {
 		return init_time, nil
 	}
@@ -459,7 +470,7 @@ func adjustTime(init_time time.Time, args []string) (time.Time, error) {
 
 	for {
 		if len(args) == 0 {
-			init_time, err = operation.Apply(init_time, operation_args)
+			init_time, err = operation.Apply(init_time, operation_args, config)
 			break
 		}
 
@@ -468,7 +479,7 @@ func adjustTime(init_time time.Time, args []string) (time.Time, error) {
 
 		new_operation, exists := operation_map[token]
 		if exists {
-			init_time, err = operation.Apply(init_time, operation_args)
+			init_time, err = operation.Apply(init_time, operation_args, config)
 			if err != nil {
 				break
 			}
@@ -486,7 +497,7 @@ func adjustTime(init_time time.Time, args []string) (time.Time, error) {
 	return init_time, nil
 }
 
-func dateTimeMathCommand(args []string) ([]AlfredItem, error) {
+func dateTimeMathCommand(config *Config, args []string) ([]AlfredItem, error) {
 	if len(args) == 0 {
 		items := []AlfredItem{
 			alfredItemFromString("Input a time", false),
@@ -503,17 +514,39 @@ func dateTimeMathCommand(args []string) ([]AlfredItem, error) {
 
 	if len(remaining_args) > 0 {
 		var err error
-		resulting_time, err = adjustTime(resulting_time, remaining_args)
+		resulting_time, err = adjustTime(resulting_time, remaining_args, config)
 		if err != nil {
 			return []AlfredItem{}, err
 		}
 	}
 
+	return timeFormatItems(config, resulting_time), nil
+}
+
+// The layout for when a time is shown in a single format
+func displayTimeLayout(config *Config) string {
+	layout, exists := config.OutputTimeFormats()["RFC3339"]
+	if !exists {
+		return time.RFC3339
+	}
+	return layout
+}
+
+// An item for the time in each of the output formats and one for the unix
+// timestamp
+func timeFormatItems(config *Config, resulting_time time.Time) []AlfredItem {
+	time_formats := config.OutputTimeFormats()
+	format_names := make([]string, 0, len(time_formats))
+	for name := range time_formats {
+		format_names = append(format_names, name)
+	}
+	sort.Strings(format_names)
+
 	// +1 is for unix timestamp
-	items := make([]AlfredItem, len(output_time_formats)+1)
+	items := make([]AlfredItem, len(time_formats)+1)
 	index := 0
-	for name, format := range output_time_formats {
-		formatted_time := resulting_time.Format(format)
+	for _, name := range format_names {
+		formatted_time := resulting_time.Format(time_formats[name])
 		items[index] = AlfredItem{
 			UID:          name,
 			Title:        formatted_time,
@@ -531,5 +564,22 @@ func dateTimeMathCommand(args []string) ([]AlfredItem, error) {
 		Autocomplete: unix_str,
 	}
 
-	return items, nil
+	return items
+}
+
+// Roughly how long a duration is in its largest unit, like "3 days"
+func roughDuration(duration time.Duration) string {
+	if duration < 0 {
+		duration = -duration
+	}
+	switch {
+	case duration >= 24*time.Hour:
+		return pluralize(int(duration/(24*time.Hour)), "day")
+	case duration >= time.Hour:
+		return pluralize(int(duration/time.Hour), "hour")
+	case duration >= time.Minute:
+		return pluralize(int(duration/time.Minute), "minute")
+	default:
+		return pluralize(int(duration/time.Second), "second")
+	}
 }
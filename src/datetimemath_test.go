@@ -9,7 +9,12 @@ const DateLayout string = "2006-01-02"
 
 func assertTime(t *testing.T, args []string, expected_time time.Time) {
 	t.Helper()
-	items, err := dateTimeMathCommand(args)
+	assertConfiguredTime(t, defaultConfig(), args, expected_time)
+}
+
+func assertConfiguredTime(t *testing.T, config *Config, args []string, expected_time time.Time) {
+	t.Helper()
+	items, err := dateTimeMathCommand(config, args)
 	if err != nil {
 		t.Fatalf("Error %s when getting next time", err.Error())
 	}
@@ -145,9 +150,9 @@ func TestAdd(t *testing.T) {
 		assertTime(t, []string{"2022-09-21", "+", "1.5year"}, test_time)
 	})
 	t.Run("AddBadFractionalYear", func(t *testing.T) {
-		res, err := dateTimeMathCommand([]string{"2022-03-21", "+", "1.3year"})
+		res, err := dateTimeMathCommand(defaultConfig(), []string{"2022-03-21", "+", "1.3year"})
 		if err == nil {
-			t.Fatalf("Expected an error, but got: %s", res)
+			t.Fatalf("Expected an error, but got: %v", res)
 		}
 	})
 	t.Run("AddMultipleOfAUnit", func(t *testing.T) {
@@ -159,15 +164,15 @@ func TestAdd(t *testing.T) {
 		assertTime(t, []string{"2022-09-21", "+", "2day", "1week"}, test_time)
 	})
 	t.Run("NotANumber", func(t *testing.T) {
-		res, err := dateTimeMathCommand([]string{"2022-09-21", "+", "one", "year"})
+		res, err := dateTimeMathCommand(defaultConfig(), []string{"2022-09-21", "+", "one", "year"})
 		if err == nil {
-			t.Fatalf("Expected an error, but got: %s", res)
+			t.Fatalf("Expected an error, but got: %v", res)
 		}
 	})
 	t.Run("NotEnoughArgs", func(t *testing.T) {
-		res, err := dateTimeMathCommand([]string{"2022-09-21", "+", "year"})
+		res, err := dateTimeMathCommand(defaultConfig(), []string{"2022-09-21", "+", "year"})
 		if err == nil {
-			t.Fatalf("Expected an error, but got: %s", res)
+			t.Fatalf("Expected an error, but got: %v", res)
 		}
 	})
 }
@@ -248,3 +253,28 @@ func TestCeil(t *testing.T) {
 		assertTime(t, []string{"2022-05-05T05:05:05Z", "end", "minute"}, test_time)
 	})
 }
+
+func TestQuotedArgs(t *testing.T) {
+	t.Run("DateAndTime", func(t *testing.T) {
+		test_time, _ := time.Parse(time.RFC3339, "2022-05-05T06:05:00Z")
+		assertTime(t, []string{"2022-05-05 05:05", "+", "1 hour"}, test_time)
+	})
+	t.Run("MultipleUnits", func(t *testing.T) {
+		test_time, _ := time.Parse(time.RFC3339, "2022-05-08T05:05:05Z")
+		assertTime(t, []string{"2022-05-05 05:05:05", "+", "2 days", "1 day"}, test_time)
+	})
+}
+
+func TestConfiguredWeekStart(t *testing.T) {
+	config := defaultConfig()
+	config.DateTimeMath.WeekStart = "Monday"
+
+	t.Run("FloorToWeek", func(t *testing.T) {
+		test_time, _ := time.Parse(time.RFC3339, "2022-05-02T00:00:00Z")
+		assertConfiguredTime(t, config, []string{"2022-05-08T05:05:05Z", "floor", "week"}, test_time)
+	})
+	t.Run("CeilToWeek", func(t *testing.T) {
+		test_time, _ := time.Parse(time.RFC3339, "2022-05-08T23:59:59.999999999Z")
+		assertConfiguredTime(t, config, []string{"2022-05-05T05:05:05Z", "ceil", "week"}, test_time)
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
+// Defaults for DevdocsConfig and CacheConfig
 const DevdocsBaseUrl string = "https://devdocs.io/"
 const CACHE_TTL int = 86400
 
@@ -36,33 +38,50 @@ type DevDocsDocIndex struct {
 	Types   []DevDocsDocType  `json:"types"`
 }
 
-func fetchDevdocsDocsList() ([]DevdocsDocSet, error) {
-	resp, err := cachedRequest(fmt.Sprintf("%s/docs/docs.json", DevdocsBaseUrl), CACHE_TTL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var docsList = []DevdocsDocSet{}
-	err = json.NewDecoder(resp.Body).Decode(&docsList)
+func devdocsUrl(config *Config, path string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(config.Devdocs.BaseURL, "/"), path)
+}
+
+func fetchDevdocsDocsList(req CommandRequest) ([]DevdocsDocSet, error) {
+	url := devdocsUrl(req.Config, "docs/docs.json")
+	docsList, err := memoryCached(url, req.Config.Cache.TTL, func() (interface{}, error) {
+		resp, err := cachedRequest(req.Env, req.Config, url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var docsList = []DevdocsDocSet{}
+		err = json.NewDecoder(resp.Body).Decode(&docsList)
+		if err != nil {
+			return nil, err
+		}
+		return docsList, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return docsList, nil
+	return docsList.([]DevdocsDocSet), nil
 }
 
-func fetchDevdocsDocIndex(docSlug string) (DevDocsDocIndex, error) {
-	url := fmt.Sprintf("%s/docs/%s/index.json", DevdocsBaseUrl, docSlug)
-	resp, err := cachedRequest(url, CACHE_TTL)
-	if err != nil {
-		return DevDocsDocIndex{}, err
-	}
-	defer resp.Body.Close()
-	var docIndex = DevDocsDocIndex{}
-	err = json.NewDecoder(resp.Body).Decode(&docIndex)
+func fetchDevdocsDocIndex(req CommandRequest, docSlug string) (DevDocsDocIndex, error) {
+	url := devdocsUrl(req.Config, fmt.Sprintf("docs/%s/index.json", docSlug))
+	docIndex, err := memoryCached(url, req.Config.Cache.TTL, func() (interface{}, error) {
+		resp, err := cachedRequest(req.Env, req.Config, url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var docIndex = DevDocsDocIndex{}
+		err = json.NewDecoder(resp.Body).Decode(&docIndex)
+		if err != nil {
+			return nil, err
+		}
+		return docIndex, nil
+	})
 	if err != nil {
 		return DevDocsDocIndex{}, err
 	}
-	return docIndex, nil
+	return docIndex.(DevDocsDocIndex), nil
 }
 
 func filterEntries(docEntries []DevDocsDocEntry, searchQuery []string) []DevDocsDocEntry {
@@ -70,60 +89,65 @@ func filterEntries(docEntries []DevDocsDocEntry, searchQuery []string) []DevDocs
 		return docEntries
 	}
 
-	matchedEntries := []DevDocsDocEntry{}
-	for _, entry := range docEntries {
-		if queryMatches(entry.Name, searchQuery) {
-			matchedEntries = append(matchedEntries, entry)
-		}
+	entryName := func(i int) string {
+		return docEntries[i].Name
+	}
+	ranked := fuzzyRank(len(docEntries), entryName, searchQuery)
+	matchedEntries := make([]DevDocsDocEntry, len(ranked))
+	for i, index := range ranked {
+		matchedEntries[i] = docEntries[index]
 	}
 	return matchedEntries
 }
 
-func devdocsSearchDoc(docSlug string, searchQuery []string) ([]AlfredItem, error) {
-	docsIndex, err := fetchDevdocsDocIndex(docSlug)
+func devdocsSearchDoc(req CommandRequest, docSlug string, searchQuery []string) ([]AlfredItem, error) {
+	docsIndex, err := fetchDevdocsDocIndex(req, docSlug)
 	if err != nil {
 		return []AlfredItem{}, err
 	}
 	entries := filterEntries(docsIndex.Entries, searchQuery)
-	if len(entries) > 25 {
-		entries = entries[:25]
+	if len(entries) > req.Config.Devdocs.MaxResults {
+		entries = entries[:req.Config.Devdocs.MaxResults]
 	}
 	docItems := make([]AlfredItem, len(entries))
 	for i, entry := range entries {
+		entryUrl := devdocsUrl(req.Config, fmt.Sprintf("%s/%s", docSlug, entry.Path))
 		docItems[i] = AlfredItem{
 			UID:          entry.Name,
 			Title:        entry.Name,
 			Subtitle:     entry.Path,
-			Arg:          []string{fmt.Sprintf("%s/%s/%s", DevdocsBaseUrl, docSlug, entry.Path)},
+			Arg:          []string{entryUrl},
 			Autocomplete: entry.Name,
-		}
+		}.withQuickLookURL(entryUrl).withText(entryUrl, entry.Name).withAction(AlfredAction{URL: []string{entryUrl}})
 	}
 	return docItems, nil
 }
 
-func devdocsCommand(args []string) ([]AlfredItem, error) {
-	docsList, err := fetchDevdocsDocsList()
+func devdocsCommand(req CommandRequest) ([]AlfredItem, error) {
+	docsList, err := fetchDevdocsDocsList(req)
 	if err != nil {
 		return nil, err
 	}
 
 	docItems := make([]AlfredItem, len(docsList))
 	for i, doc := range docsList {
+		title := fmt.Sprintf("%s %s", doc.Name, doc.Release)
 		docItems[i] = AlfredItem{
 			UID:          doc.Slug,
-			Title:        fmt.Sprintf("%s %s", doc.Name, doc.Release),
+			Title:        title,
 			Subtitle:     doc.Slug,
 			Arg:          []string{fmt.Sprintf("%s ", doc.Slug)},
 			Autocomplete: doc.Name,
-		}
+		}.withMatch(fmt.Sprintf("%s %s", title, doc.Slug))
 	}
 
-	docItems = filterAlfredItems(docItems, args)
+	docItems = filterAlfredItems(docItems, req.Args)
 
 	return docItems, nil
 }
 
-func devdocsDocSetCommand(args []string) ([]AlfredItem, error) {
+func devdocsDocSetCommand(req CommandRequest) ([]AlfredItem, error) {
+	args := req.Args
 	if len(args) == 0 {
 		return []AlfredItem{}, errors.New("No doc set specified")
 	}
@@ -132,5 +156,5 @@ func devdocsDocSetCommand(args []string) ([]AlfredItem, error) {
 	if len(args) > 1 {
 		searchQuery = args[1:]
 	}
-	return devdocsSearchDoc(args[0], searchQuery)
+	return devdocsSearchDoc(req, args[0], searchQuery)
 }
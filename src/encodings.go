@@ -0,0 +1,439 @@
+package ralphred
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime/quotedprintable"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Decoded bytes that aren't valid UTF-8 are shown escaped so they can be read
+func decodedString(decoded []byte) string {
+	if utf8.Valid(decoded) {
+		return string(decoded)
+	}
+	quoted := strconv.Quote(string(decoded))
+	return quoted[1 : len(quoted)-1]
+}
+
+func base64Conversions(name string, description string, encoding *base64.Encoding) map[string]StringConversion {
+	return map[string]StringConversion{
+		name: {
+			Description: fmt.Sprintf("Encode the string as %s", description),
+			Convert: func(input_string string) (string, error) {
+				return encoding.EncodeToString([]byte(input_string)), nil
+			},
+		},
+		"un" + name: {
+			Description: fmt.Sprintf("Decode the string from %s", description),
+			Convert: func(input_string string) (string, error) {
+				decoded, err := encoding.DecodeString(input_string)
+				var corrupt base64.CorruptInputError
+				if errors.As(err, &corrupt) {
+					return "", fmt.Errorf("Invalid %s at character %d", description, int64(corrupt)+1)
+				} else if err != nil {
+					return "", err
+				}
+				return decodedString(decoded), nil
+			},
+		},
+	}
+}
+
+func decodeHex(input_string string) (string, error) {
+	for i, char := range input_string {
+		if char > 0x7f || !isHexDigit(byte(char)) {
+			return "", fmt.Errorf("Invalid hex at character %d: %q", i+1, char)
+		}
+	}
+	if len(input_string)%2 != 0 {
+		return "", fmt.Errorf("Invalid hex, odd number of characters (%d)", len(input_string))
+	}
+	decoded, err := hex.DecodeString(input_string)
+	if err != nil {
+		return "", err
+	}
+	return decodedString(decoded), nil
+}
+
+// url.EscapeError only has the bad escape, so find where it is in the input
+func urlUnescapeError(input_string string, err error) error {
+	var escapeErr url.EscapeError
+	if errors.As(err, &escapeErr) {
+		position := strings.Index(input_string, string(escapeErr)) + 1
+		return fmt.Errorf("Invalid URL escape %q at character %d", string(escapeErr), position)
+	}
+	return err
+}
+
+// The mime reader passes invalid escapes through, so check them first to be
+// able to say where the input is invalid
+func validateQuotedPrintable(input_string string) error {
+	for lineNum, line := range strings.Split(input_string, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		for i := 0; i < len(line); i++ {
+			if line[i] != '=' || i == len(line)-1 {
+				continue
+			}
+			escape := line[i+1:]
+			if len(escape) < 2 || !isHexDigit(escape[0]) || !isHexDigit(escape[1]) {
+				return fmt.Errorf("Invalid quoted-printable escape on line %d at character %d", lineNum+1, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+func isHexDigit(char byte) bool {
+	return strings.IndexByte("0123456789abcdefABCDEF", char) != -1
+}
+
+func decodeQuotedPrintable(input_string string) (string, error) {
+	err := validateQuotedPrintable(input_string)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(input_string)))
+	if err != nil {
+		return "", fmt.Errorf("Invalid quoted-printable: %s", strings.TrimPrefix(err.Error(), "quotedprintable: "))
+	}
+	return decodedString(decoded), nil
+}
+
+func encodeQuotedPrintable(input_string string) (string, error) {
+	var encoded bytes.Buffer
+	writer := quotedprintable.NewWriter(&encoded)
+	_, err := writer.Write([]byte(input_string))
+	if err != nil {
+		return "", err
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+	return encoded.String(), nil
+}
+
+var encoding_conversions = mergeConversions(
+	base64Conversions("base64", "base64", base64.StdEncoding),
+	base64Conversions("base64raw", "unpadded base64", base64.RawStdEncoding),
+	base64Conversions("base64url", "URL safe base64", base64.URLEncoding),
+	base64Conversions("base64urlraw", "unpadded URL safe base64", base64.RawURLEncoding),
+	map[string]StringConversion{
+		"base32": {
+			Description: "Encode the string as base32",
+			Convert: func(input_string string) (string, error) {
+				return base32.StdEncoding.EncodeToString([]byte(input_string)), nil
+			},
+		},
+		"unbase32": {
+			Description: "Decode the string from base32",
+			Convert: func(input_string string) (string, error) {
+				decoded, err := base32.StdEncoding.DecodeString(input_string)
+				var corrupt base32.CorruptInputError
+				if errors.As(err, &corrupt) {
+					return "", fmt.Errorf("Invalid base32 at character %d", int64(corrupt)+1)
+				} else if err != nil {
+					return "", err
+				}
+				return decodedString(decoded), nil
+			},
+		},
+		"hex": {
+			Description: "Encode the string as hex",
+			Convert: func(input_string string) (string, error) {
+				return hex.EncodeToString([]byte(input_string)), nil
+			},
+		},
+		"unhex": {
+			Description: "Decode the string from hex",
+			Convert:     decodeHex,
+		},
+		"urlquery": {
+			Description: "Escape the string for a URL query",
+			Convert: func(input_string string) (string, error) {
+				return url.QueryEscape(input_string), nil
+			},
+		},
+		"unurlquery": {
+			Description: "Unescape a URL query string",
+			Convert: func(input_string string) (string, error) {
+				unescaped, err := url.QueryUnescape(input_string)
+				if err != nil {
+					return "", urlUnescapeError(input_string, err)
+				}
+				return unescaped, nil
+			},
+		},
+		"urlpath": {
+			Description: "Escape the string for a URL path segment",
+			Convert: func(input_string string) (string, error) {
+				return url.PathEscape(input_string), nil
+			},
+		},
+		"unurlpath": {
+			Description: "Unescape a URL path segment",
+			Convert: func(input_string string) (string, error) {
+				unescaped, err := url.PathUnescape(input_string)
+				if err != nil {
+					return "", urlUnescapeError(input_string, err)
+				}
+				return unescaped, nil
+			},
+		},
+		"html": {
+			Description: "Escape HTML special characters as entities",
+			Convert: func(input_string string) (string, error) {
+				return html.EscapeString(input_string), nil
+			},
+		},
+		"unhtml": {
+			Description: "Unescape HTML entities",
+			Convert: func(input_string string) (string, error) {
+				return html.UnescapeString(input_string), nil
+			},
+		},
+		"quotedprintable": {
+			Description: "Encode the string as quoted-printable",
+			Convert:     encodeQuotedPrintable,
+		},
+		"unquotedprintable": {
+			Description: "Decode the string from quoted-printable",
+			Convert:     decodeQuotedPrintable,
+		},
+		"punycode": {
+			Description: "Encode a domain name with punycode",
+			Convert:     domainToPunycode,
+		},
+		"unpunycode": {
+			Description: "Decode a punycode domain name",
+			Convert:     domainFromPunycode,
+		},
+	},
+)
+
+func mergeConversions(groups ...map[string]StringConversion) map[string]StringConversion {
+	merged := map[string]StringConversion{}
+	for _, group := range groups {
+		for name, conversion := range group {
+			merged[name] = conversion
+		}
+	}
+	return merged
+}
+
+// Punycode parameters from RFC 3492
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodePrefix      = "xn--"
+)
+
+func punycodeAdapt(delta int, numPoints int, firstTime bool) int {
+	if firstTime {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+func punycodeThreshold(k int, bias int) int {
+	if k <= bias {
+		return punycodeTMin
+	} else if k >= bias+punycodeTMax {
+		return punycodeTMax
+	}
+	return k - bias
+}
+
+func punycodeDigit(digit int) byte {
+	if digit < 26 {
+		return byte('a' + digit)
+	}
+	return byte('0' + digit - 26)
+}
+
+func punycodeDigitValue(char byte) (int, bool) {
+	switch {
+	case char >= '0' && char <= '9':
+		return int(char-'0') + 26, true
+	case char >= 'a' && char <= 'z':
+		return int(char - 'a'), true
+	case char >= 'A' && char <= 'Z':
+		return int(char - 'A'), true
+	}
+	return 0, false
+}
+
+func encodePunycode(input string) string {
+	runes := []rune(input)
+	var output strings.Builder
+	for _, char := range runes {
+		if char < 0x80 {
+			output.WriteRune(char)
+		}
+	}
+	basicCount := output.Len()
+	handled := basicCount
+	if basicCount > 0 {
+		output.WriteByte('-')
+	}
+
+	n := punycodeInitialN
+	delta := 0
+	bias := punycodeInitialBias
+	for handled < len(runes) {
+		next := int(^uint(0) >> 1)
+		for _, char := range runes {
+			if int(char) >= n && int(char) < next {
+				next = int(char)
+			}
+		}
+		delta += (next - n) * (handled + 1)
+		n = next
+		for _, char := range runes {
+			if int(char) < n {
+				delta++
+			}
+			if int(char) != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := punycodeThreshold(k, bias)
+				if q < t {
+					break
+				}
+				output.WriteByte(punycodeDigit(t + (q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			output.WriteByte(punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basicCount)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return output.String()
+}
+
+type punycodeError struct {
+	// 1 based offset of the character that made the input invalid
+	Position int
+	Reason   string
+}
+
+func (err punycodeError) Error() string {
+	return fmt.Sprintf("Invalid punycode at character %d: %s", err.Position, err.Reason)
+}
+
+func decodePunycode(input string) (string, error) {
+	output := []rune{}
+	basicEnd := strings.LastIndexByte(input, '-')
+	start := 0
+	if basicEnd > 0 {
+		for i := 0; i < basicEnd; i++ {
+			if input[i] >= 0x80 {
+				return "", punycodeError{i + 1, "non-ASCII character"}
+			}
+			output = append(output, rune(input[i]))
+		}
+		start = basicEnd + 1
+	}
+
+	n := punycodeInitialN
+	bias := punycodeInitialBias
+	i := 0
+	for position := start; position < len(input); {
+		oldi := i
+		w := 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if position >= len(input) {
+				return "", punycodeError{position + 1, "input ends mid character"}
+			}
+			digit, ok := punycodeDigitValue(input[position])
+			if !ok {
+				return "", punycodeError{position + 1, fmt.Sprintf("%q isn't a punycode digit", input[position])}
+			}
+			position++
+			i += digit * w
+			t := punycodeThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			w *= punycodeBase - t
+			if i > utf8.MaxRune || w > utf8.MaxRune {
+				return "", punycodeError{position, "value out of range"}
+			}
+		}
+		bias = punycodeAdapt(i-oldi, len(output)+1, oldi == 0)
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > utf8.MaxRune {
+			return "", punycodeError{position, "value out of range"}
+		}
+		output = append(output[:i], append([]rune{rune(n)}, output[i:]...)...)
+		i++
+	}
+	return string(output), nil
+}
+
+// Domains are converted label by label, only labels with non-ASCII
+// characters are encoded
+func domainToPunycode(input_string string) (string, error) {
+	labels := strings.Split(input_string, ".")
+	for i, label := range labels {
+		label = strings.ToLower(label)
+		for _, char := range label {
+			if char >= 0x80 {
+				label = punycodePrefix + encodePunycode(label)
+				break
+			}
+		}
+		labels[i] = label
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func domainFromPunycode(input_string string) (string, error) {
+	labels := strings.Split(input_string, ".")
+	offset := 0
+	for i, label := range labels {
+		if strings.HasPrefix(strings.ToLower(label), punycodePrefix) {
+			decoded, err := decodePunycode(label[len(punycodePrefix):])
+			var punyErr punycodeError
+			if errors.As(err, &punyErr) {
+				// Make the position relative to the whole input
+				punyErr.Position += offset + len(punycodePrefix)
+				return "", punyErr
+			} else if err != nil {
+				return "", err
+			}
+			labels[i] = decoded
+		}
+		offset += len(label) + 1
+	}
+	return strings.Join(labels, "."), nil
+}
@@ -0,0 +1,91 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func assertStringCommandError(t *testing.T, input []string, expected string) {
+	t.Helper()
+	_, err := stringCommand(defaultConfig(), input)
+	if err == nil {
+		t.Fatalf("Expected an error containing %s", expected)
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("Got %s expected it to contain %s", err, expected)
+	}
+}
+
+func TestBase64(t *testing.T) {
+	t.Run("Encode", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"base64", "hi?>"}, "aGk/Pg==")
+		assertStringCommandResult(t, []string{"base64url", "hi?>"}, "aGk_Pg==")
+		assertStringCommandResult(t, []string{"base64raw", "hi?>"}, "aGk/Pg")
+		assertStringCommandResult(t, []string{"base64urlraw", "hi?>"}, "aGk_Pg")
+	})
+	t.Run("Decode", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"unbase64", "aGk/Pg=="}, "hi?>")
+		assertStringCommandResult(t, []string{"unbase64url", "aGk_Pg=="}, "hi?>")
+		assertStringCommandResult(t, []string{"unbase64raw", "aGk/Pg"}, "hi?>")
+		assertStringCommandResult(t, []string{"unbase64urlraw", "aGk_Pg"}, "hi?>")
+	})
+	t.Run("BinaryIsEscaped", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"unbase64", "/wA="}, "\\xff\\x00")
+	})
+	t.Run("InvalidCharacter", func(t *testing.T) {
+		assertStringCommandError(t, []string{"unbase64", "aGk_Pg=="}, "Invalid base64 at character 4")
+	})
+}
+
+func TestBase32(t *testing.T) {
+	assertStringCommandResult(t, []string{"base32", "hi"}, "NBUQ====")
+	assertStringCommandResult(t, []string{"unbase32", "NBUQ===="}, "hi")
+	assertStringCommandError(t, []string{"unbase32", "NB1Q===="}, "Invalid base32 at character 3")
+}
+
+func TestHex(t *testing.T) {
+	assertStringCommandResult(t, []string{"hex", "hi"}, "6869")
+	assertStringCommandResult(t, []string{"unhex", "6869"}, "hi")
+	assertStringCommandError(t, []string{"unhex", "68g9"}, "Invalid hex at character 3")
+	assertStringCommandError(t, []string{"unhex", "686"}, "odd number of characters")
+}
+
+func TestURLEscaping(t *testing.T) {
+	t.Run("Query", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"urlquery", "a b&c"}, "a+b%26c")
+		assertStringCommandResult(t, []string{"unurlquery", "a+b%26c"}, "a b&c")
+	})
+	t.Run("Path", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"urlpath", "a b/c"}, "a%20b%2Fc")
+		assertStringCommandResult(t, []string{"unurlpath", "a%20b%2Fc"}, "a b/c")
+	})
+	t.Run("InvalidEscape", func(t *testing.T) {
+		assertStringCommandError(t, []string{"unurlquery", "abc%zz"}, "at character 4")
+	})
+}
+
+func TestHTMLEntities(t *testing.T) {
+	assertStringCommandResult(t, []string{"html", "<a href=\"x\">"}, "&lt;a href=&#34;x&#34;&gt;")
+	assertStringCommandResult(t, []string{"unhtml", "&lt;b&gt; &amp; &eacute;"}, "<b> & é")
+}
+
+func TestQuotedPrintable(t *testing.T) {
+	assertStringCommandResult(t, []string{"quotedprintable", "café"}, "caf=C3=A9")
+	assertStringCommandResult(t, []string{"unquotedprintable", "caf=C3=A9"}, "café")
+	assertStringCommandError(t, []string{"unquotedprintable", "ok\ncaf=Z9"}, "on line 2 at character 4")
+}
+
+func TestPunycode(t *testing.T) {
+	t.Run("Encode", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"punycode", "münchen.de"}, "xn--mnchen-3ya.de")
+		assertStringCommandResult(t, []string{"punycode", "Bücher.example"}, "xn--bcher-kva.example")
+		assertStringCommandResult(t, []string{"punycode", "例え.jp"}, "xn--r8jz45g.jp")
+	})
+	t.Run("Decode", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"unpunycode", "xn--mnchen-3ya.de"}, "münchen.de")
+		assertStringCommandResult(t, []string{"unpunycode", "xn--r8jz45g.jp"}, "例え.jp")
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		assertStringCommandError(t, []string{"unpunycode", "www.xn--mnchen-3y!a.de"}, "Invalid punycode at character 18")
+	})
+}
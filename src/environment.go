@@ -0,0 +1,93 @@
+package ralphred
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Environment is where ralphred keeps things on disk. Alfred tells workflows
+// where to store things with alfred_* variables, and outside of alfred the
+// XDG directories are used
+type Environment struct {
+	// Files that can be deleted at any time, like cached responses
+	CacheDir string
+	// Files that should be kept
+	DataDir string
+	// Where config.json is looked up
+	ConfigDir string
+	// Bundle id of the alfred workflow, empty when not run from alfred
+	BundleID string
+	// Set when alfred's workflow debugger is open
+	Debug bool
+}
+
+// Extra logging that's only wanted when debugging
+func (env Environment) debugf(format string, v ...interface{}) {
+	if env.Debug {
+		log.Printf(format, v...)
+	}
+}
+
+// Resolve a ralphred directory from the alfred variable, falling back to the
+// XDG variable and then the default base directory
+func resolveDir(getenv func(string) string, alfredVar string, xdgVar string, defaultBase func() (string, error)) (string, error) {
+	if dir := getenv(alfredVar); dir != "" {
+		return dir, nil
+	}
+	base := getenv(xdgVar)
+	if base == "" {
+		var err error
+		base, err = defaultBase()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(base, "ralphred"), nil
+}
+
+func homeSubdir(parts ...string) func() (string, error) {
+	return func() (string, error) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(append([]string{homeDir}, parts...)...), nil
+	}
+}
+
+func loadEnvironment(getenv func(string) string) (Environment, error) {
+	env := Environment{
+		BundleID: getenv("alfred_workflow_bundleid"),
+		Debug:    getenv("alfred_debug") == "1",
+	}
+
+	var err error
+	// The platform cache dir is the default so existing caches keep working
+	env.CacheDir, err = resolveDir(getenv, "alfred_workflow_cache", "XDG_CACHE_HOME", os.UserCacheDir)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	env.DataDir, err = resolveDir(getenv, "alfred_workflow_data", "XDG_DATA_HOME", homeSubdir(".local", "share"))
+	if err != nil {
+		return Environment{}, err
+	}
+
+	// Alfred doesn't have a config dir, so config lives with the data
+	env.ConfigDir, err = resolveDir(getenv, "alfred_workflow_data", "XDG_CONFIG_HOME", homeSubdir(".config"))
+	if err != nil {
+		return Environment{}, err
+	}
+
+	return env, nil
+}
+
+// Prefixes of the environment variables that change how a command runs. The
+// daemon gets these from the CLI so it runs commands the same way
+var environmentVarPrefixes = []string{"alfred_", "RALPHRED_", "XDG_"}
+
+// Variables that are read from the process rather than through getenv, like
+// TZ for time.Local and HOME for the default dirs. The daemon can't change
+// these for a request, so it refuses runs where they differ from its own
+var processEnvironmentVars = []string{"TZ", "HOME"}
@@ -0,0 +1,70 @@
+package ralphred
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvironment(t *testing.T) {
+	loadTestEnvironment := func(t *testing.T, vars map[string]string) Environment {
+		t.Helper()
+		env, err := loadEnvironment(func(name string) string { return vars[name] })
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		return env
+	}
+
+	t.Run("AlfredVariables", func(t *testing.T) {
+		env := loadTestEnvironment(t, map[string]string{
+			"alfred_workflow_cache":    "/alfred/cache",
+			"alfred_workflow_data":     "/alfred/data",
+			"alfred_workflow_bundleid": "com.kdeal.ralphred",
+			"alfred_debug":             "1",
+			"XDG_CACHE_HOME":           "/xdg/cache",
+		})
+		expected := Environment{
+			CacheDir:  "/alfred/cache",
+			DataDir:   "/alfred/data",
+			ConfigDir: "/alfred/data",
+			BundleID:  "com.kdeal.ralphred",
+			Debug:     true,
+		}
+		if env != expected {
+			t.Fatalf("Got %+v expected %+v", env, expected)
+		}
+	})
+	t.Run("XDGFallback", func(t *testing.T) {
+		env := loadTestEnvironment(t, map[string]string{
+			"XDG_CACHE_HOME":  "/xdg/cache",
+			"XDG_DATA_HOME":   "/xdg/data",
+			"XDG_CONFIG_HOME": "/xdg/config",
+		})
+		expected := Environment{
+			CacheDir:  filepath.Join("/xdg/cache", "ralphred"),
+			DataDir:   filepath.Join("/xdg/data", "ralphred"),
+			ConfigDir: filepath.Join("/xdg/config", "ralphred"),
+		}
+		if env != expected {
+			t.Fatalf("Got %+v expected %+v", env, expected)
+		}
+	})
+	t.Run("DebugOnlyWhenOne", func(t *testing.T) {
+		env := loadTestEnvironment(t, map[string]string{"alfred_debug": "0"})
+		if env.Debug {
+			t.Fatal("Debug was turned on")
+		}
+	})
+}
+
+func TestConfigCacheDir(t *testing.T) {
+	env := Environment{CacheDir: "/alfred/cache"}
+	config := defaultConfig()
+	if config.CacheDir(env) != "/alfred/cache" {
+		t.Fatalf("Got %s expected the environment's cache dir", config.CacheDir(env))
+	}
+	config.Cache.Dir = "/configured"
+	if config.CacheDir(env) != "/configured" {
+		t.Fatalf("Got %s expected the configured cache dir", config.CacheDir(env))
+	}
+}
@@ -0,0 +1,473 @@
+package ralphred
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type escapeTarget struct {
+	Name        string
+	Description string
+	Escape      func(string) (string, error)
+	Unescape    func(string) (string, error)
+}
+
+func escapeShell(input_string string) (string, error) {
+	return "'" + strings.ReplaceAll(input_string, "'", `'\''`) + "'", nil
+}
+
+// Shell words are split the same way queries are, so the tokenizer does the
+// unquoting
+func unescapeShell(input_string string) (string, error) {
+	tokens := tokenizeQuery(strings.TrimSpace(input_string))
+	if len(tokens) != 1 {
+		return "", fmt.Errorf("Expected a single shell word got %d", len(tokens))
+	}
+	return tokens[0].Value, nil
+}
+
+func escapeJSONString(input_string string) (string, error) {
+	return marshalJSON(input_string, "")
+}
+
+func unescapeJSONString(input_string string) (string, error) {
+	var value string
+	err := json.Unmarshal([]byte(strings.TrimSpace(input_string)), &value)
+	if err != nil {
+		return "", errors.New("Expected a JSON string")
+	}
+	return value, nil
+}
+
+func unescapeGoString(input_string string) (string, error) {
+	value, err := strconv.Unquote(strings.TrimSpace(input_string))
+	if err != nil {
+		return "", errors.New("Expected a Go string literal")
+	}
+	return value, nil
+}
+
+func escapeRegex(input_string string) (string, error) {
+	return regexp.QuoteMeta(input_string), nil
+}
+
+// Only escaped punctuation is allowed, escapes like \d match a class of
+// characters so they can't be unescaped
+func unescapeRegex(input_string string) (string, error) {
+	var unescaped strings.Builder
+	for i := 0; i < len(input_string); i++ {
+		char := input_string[i]
+		if char != '\\' {
+			unescaped.WriteByte(char)
+			continue
+		}
+		if i+1 == len(input_string) {
+			return "", errors.New("Pattern ends with a \\")
+		}
+		i++
+		if escaped := rune(input_string[i]); unicode.IsLetter(escaped) || unicode.IsDigit(escaped) {
+			return "", fmt.Errorf("\\%c at character %d isn't an escaped character", input_string[i], i)
+		}
+		unescaped.WriteByte(input_string[i])
+	}
+	return unescaped.String(), nil
+}
+
+func escapeSQL(input_string string) (string, error) {
+	return "'" + strings.ReplaceAll(input_string, "'", "''") + "'", nil
+}
+
+func unescapeSQL(input_string string) (string, error) {
+	input_string = strings.TrimSpace(input_string)
+	if len(input_string) < 2 || input_string[0] != '\'' || input_string[len(input_string)-1] != '\'' {
+		return "", errors.New("Expected a string in single quotes")
+	}
+	inner := input_string[1 : len(input_string)-1]
+	if strings.Count(strings.ReplaceAll(inner, "''", ""), "'") > 0 {
+		return "", errors.New("Single quotes in the string need to be doubled")
+	}
+	return strings.ReplaceAll(inner, "''", "'"), nil
+}
+
+// Whitespace is escaped too, attribute values have it normalized to spaces
+var xmlAttributeEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+	"\t", "&#9;",
+	"\n", "&#10;",
+	"\r", "&#13;",
+)
+
+func escapeXMLAttribute(input_string string) (string, error) {
+	return `"` + xmlAttributeEscaper.Replace(input_string) + `"`, nil
+}
+
+func unescapeXMLAttribute(input_string string) (string, error) {
+	input_string = strings.TrimSpace(input_string)
+	if len(input_string) >= 2 && (input_string[0] == '"' || input_string[0] == '\'') && input_string[len(input_string)-1] == input_string[0] {
+		input_string = input_string[1 : len(input_string)-1]
+	}
+	return html.UnescapeString(input_string), nil
+}
+
+func escapeCSV(input_string string) (string, error) {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	err := writer.Write([]string{input_string})
+	if err != nil {
+		return "", err
+	}
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n"), writer.Error()
+}
+
+func unescapeCSV(input_string string) (string, error) {
+	fields, err := csv.NewReader(strings.NewReader(input_string)).Read()
+	if err != nil {
+		return "", fmt.Errorf("Invalid CSV: %s", err)
+	}
+	if len(fields) != 1 {
+		return "", fmt.Errorf("Expected a single CSV field got %d", len(fields))
+	}
+	return fields[0], nil
+}
+
+var c_simple_escapes = map[rune]string{
+	'\a': `\a`, '\b': `\b`, '\f': `\f`, '\n': `\n`, '\r': `\r`, '\t': `\t`, '\v': `\v`,
+	'\\': `\\`, '"': `\"`,
+}
+
+// Control characters are octal in C since \x takes as many hex digits as
+// follow it. Other characters are left as UTF-8
+func escapeCString(input_string string) (string, error) {
+	var escaped strings.Builder
+	escaped.WriteByte('"')
+	for _, char := range input_string {
+		if simple, exists := c_simple_escapes[char]; exists {
+			escaped.WriteString(simple)
+		} else if char < 0x20 || char == 0x7f {
+			escaped.WriteString(fmt.Sprintf("\\%03o", char))
+		} else {
+			escaped.WriteRune(char)
+		}
+	}
+	escaped.WriteByte('"')
+	return escaped.String(), nil
+}
+
+// JS strings can't have raw line or paragraph separators in older engines
+func escapeJSString(input_string string) (string, error) {
+	var escaped strings.Builder
+	escaped.WriteByte('"')
+	for _, char := range input_string {
+		if simple, exists := c_simple_escapes[char]; exists && char != '\a' {
+			escaped.WriteString(simple)
+		} else if char < 0x20 || char == 0x7f {
+			escaped.WriteString(fmt.Sprintf("\\x%02x", char))
+		} else if char == 0x2028 || char == 0x2029 {
+			escaped.WriteString(fmt.Sprintf("\\u%04x", char))
+		} else {
+			escaped.WriteRune(char)
+		}
+	}
+	escaped.WriteByte('"')
+	return escaped.String(), nil
+}
+
+var c_unescapes = map[byte]string{
+	'a': "\a", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v",
+	'\\': "\\", '"': "\"", '\'': "'", '?': "?",
+}
+
+func isHexString(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if !isHexDigit(str[i]) {
+			return false
+		}
+	}
+	return str != ""
+}
+
+// Unescape a C or JS string literal. They share most escapes, C has octal
+// and \U while JS has \u{...} and line continuations
+func unescapeCStyleString(input_string string, js bool) (string, error) {
+	input_string = strings.TrimSpace(input_string)
+	quotes := `"`
+	if js {
+		quotes = "\"'`"
+	}
+	if len(input_string) < 2 || !strings.ContainsRune(quotes, rune(input_string[0])) || input_string[len(input_string)-1] != input_string[0] {
+		return "", errors.New("Expected a quoted string literal")
+	}
+	inner := input_string[1 : len(input_string)-1]
+
+	var unescaped strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] != '\\' {
+			unescaped.WriteByte(inner[i])
+			continue
+		}
+		position := i + 2
+		if i+1 == len(inner) {
+			return "", errors.New("String ends with a \\")
+		}
+		i++
+		escape := inner[i]
+		if simple, exists := c_unescapes[escape]; exists && (escape != 'a' || !js) {
+			unescaped.WriteString(simple)
+			continue
+		}
+
+		digits := ""
+		base := 16
+		switch {
+		case js && escape == '\n':
+			continue
+		case escape >= '0' && escape <= '7' && !js:
+			end := i
+			for end < len(inner) && end < i+3 && inner[end] >= '0' && inner[end] <= '7' {
+				end++
+			}
+			digits, base = inner[i:end], 8
+			i = end - 1
+		case js && escape == '0' && (i+1 == len(inner) || inner[i+1] < '0' || inner[i+1] > '9'):
+			digits = "0"
+		case escape == 'x':
+			end := i + 1
+			for end < len(inner) && isHexDigit(inner[end]) && (!js || end < i+3) {
+				end++
+			}
+			digits = inner[i+1 : end]
+			i = end - 1
+			if js && len(digits) != 2 {
+				return "", fmt.Errorf("Invalid \\x escape at character %d, expected 2 hex digits", position)
+			}
+		case escape == 'u' && js && i+1 < len(inner) && inner[i+1] == '{':
+			end := strings.IndexByte(inner[i:], '}')
+			if end == -1 {
+				return "", fmt.Errorf("Missing } for \\u{ at character %d", position)
+			}
+			digits = inner[i+2 : i+end]
+			i += end
+		case escape == 'u' || escape == 'U' && !js:
+			length := 4
+			if escape == 'U' {
+				length = 8
+			}
+			if i+1+length <= len(inner) {
+				digits = inner[i+1 : i+1+length]
+			}
+			i += length
+		default:
+			return "", fmt.Errorf("Unknown escape \\%c at character %d", escape, position)
+		}
+
+		if base == 16 && !isHexString(digits) {
+			return "", fmt.Errorf("Invalid \\%c escape at character %d", escape, position)
+		}
+		value, err := strconv.ParseUint(digits, base, 32)
+		if err != nil || value > utf8.MaxRune {
+			return "", fmt.Errorf("Invalid \\%c escape at character %d", escape, position)
+		}
+		if (escape == 'x' || base == 8) && !js {
+			// Octal and hex escapes are bytes in C
+			if value > 0xff {
+				return "", fmt.Errorf("%s at character %d is more than a byte", inner[position-2:i+1], position)
+			}
+			unescaped.WriteByte(byte(value))
+			continue
+		}
+
+		char := rune(value)
+		if js && utf16.IsSurrogate(char) {
+			// Characters past U+FFFF are written as a pair of \u escapes
+			low, ok := jsLowSurrogate(inner[i+1:])
+			char = utf16.DecodeRune(char, low)
+			if !ok || char == utf8.RuneError {
+				return "", fmt.Errorf("Unpaired surrogate %s at character %d", inner[position-2:i+1], position)
+			}
+			i += 6
+		}
+		unescaped.WriteRune(char)
+	}
+	return unescaped.String(), nil
+}
+
+// The low surrogate from a \uXXXX escape at the start of the string
+func jsLowSurrogate(str string) (rune, bool) {
+	if len(str) < 6 || !strings.HasPrefix(str, "\\u") || !isHexString(str[2:6]) {
+		return 0, false
+	}
+	value, _ := strconv.ParseUint(str[2:6], 16, 32)
+	return rune(value), true
+}
+
+var escape_targets = []escapeTarget{
+	{
+		Name:        "shell",
+		Description: "POSIX shell single quoted",
+		Escape:      escapeShell,
+		Unescape:    unescapeShell,
+	},
+	{
+		Name:        "go",
+		Description: "Go string literal",
+		Escape: func(input_string string) (string, error) {
+			return strconv.Quote(input_string), nil
+		},
+		Unescape: unescapeGoString,
+	},
+	{
+		Name:        "json",
+		Description: "JSON string",
+		Escape:      escapeJSONString,
+		Unescape:    unescapeJSONString,
+	},
+	{
+		Name:        "regex",
+		Description: "Regex with metacharacters escaped",
+		Escape:      escapeRegex,
+		Unescape:    unescapeRegex,
+	},
+	{
+		Name:        "sql",
+		Description: "SQL string literal",
+		Escape:      escapeSQL,
+		Unescape:    unescapeSQL,
+	},
+	{
+		Name:        "xml",
+		Description: "XML attribute value",
+		Escape:      escapeXMLAttribute,
+		Unescape:    unescapeXMLAttribute,
+	},
+	{
+		Name:        "csv",
+		Description: "CSV field",
+		Escape:      escapeCSV,
+		Unescape:    unescapeCSV,
+	},
+	{
+		Name:        "c",
+		Description: "C string literal",
+		Escape:      escapeCString,
+		Unescape: func(input_string string) (string, error) {
+			return unescapeCStyleString(input_string, false)
+		},
+	},
+	{
+		Name:        "js",
+		Description: "JavaScript string literal",
+		Escape:      escapeJSString,
+		Unescape: func(input_string string) (string, error) {
+			return unescapeCStyleString(input_string, true)
+		},
+	},
+}
+
+// Look up a target by name, names starting with un are for unescaping
+func lookupEscapeTarget(name string) (escapeTarget, bool, bool) {
+	name = strings.ToLower(name)
+	unescape := strings.HasPrefix(name, "un")
+	for _, target := range escape_targets {
+		if target.Name == name {
+			return target, false, true
+		} else if unescape && target.Name == name[2:] {
+			return target, true, true
+		}
+	}
+	return escapeTarget{}, false, false
+}
+
+func escapeCommands(searchQuery []string) []AlfredItem {
+	items := []AlfredItem{}
+	for _, prefix := range []string{"", "un"} {
+		for _, target := range escape_targets {
+			name := prefix + target.Name
+			description := fmt.Sprintf("Escape as a %s", target.Description)
+			if prefix != "" {
+				description = fmt.Sprintf("Unescape a %s", target.Description)
+			}
+			items = append(items, AlfredItem{
+				UID:          name,
+				Title:        name,
+				Subtitle:     description,
+				Arg:          []string{name + " "},
+				Autocomplete: name,
+			})
+		}
+	}
+	return filterAlfredItems(items, searchQuery)
+}
+
+func escapeItem(target escapeTarget, result string, unescaped bool) AlfredItem {
+	subtitle := target.Description
+	if unescaped {
+		subtitle = fmt.Sprintf("Unescaped %s", target.Description)
+	}
+	item := alfredItemFromString(result, false).withSubtitle(subtitle)
+	item.UID = target.Name
+	return item
+}
+
+// Escape text for a target, or for every target if the first arg isn't the
+// name of one. "un" with no target tries every unescape. The text is used
+// exactly as typed, quotes and backslashes included
+func escapeCommand(req CommandRequest) ([]AlfredItem, error) {
+	args := req.Args
+	if len(args) == 0 {
+		return escapeCommands([]string{}), nil
+	}
+
+	target, unescape, exists := lookupEscapeTarget(args[0])
+	if exists && len(args) == 1 {
+		return escapeCommands(args), nil
+	} else if exists {
+		convert := target.Escape
+		if unescape {
+			convert = target.Unescape
+		}
+		result, err := convert(req.RawFrom(1))
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return []AlfredItem{escapeItem(target, result, unescape)}, nil
+	}
+
+	items := []AlfredItem{}
+	if args[0] == "un" && len(args) == 1 {
+		return escapeCommands(args), nil
+	} else if args[0] == "un" {
+		// Results that match the input aren't escaped for that target
+		input := req.RawFrom(1)
+		for _, target := range escape_targets {
+			if result, err := target.Unescape(input); err == nil && result != input {
+				items = append(items, escapeItem(target, result, true))
+			}
+		}
+		if len(items) == 0 {
+			return items, errors.New("The text isn't escaped for any of the targets")
+		}
+		return items, nil
+	}
+	for _, target := range escape_targets {
+		result, err := target.Escape(req.RawFrom(0))
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		items = append(items, escapeItem(target, result, false))
+	}
+	return items, nil
+}
@@ -0,0 +1,162 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeTargets(t *testing.T) {
+	input := "it's a \"test\"\n\t\\ <x> & 1,2 $5.00\x01"
+	cases := map[string]string{
+		"shell": `'it'\''s a "test"` + "\n\t" + `\ <x> & 1,2 $5.00` + "\x01'",
+		"go":    `"it's a \"test\"\n\t\\ <x> & 1,2 $5.00\x01"`,
+		"json":  `"it's a \"test\"\n\t\\ <x> & 1,2 $5.00\u0001"`,
+		"regex": "it's a \"test\"\n\t\\\\ <x> & 1,2 \\$5\\.00\x01",
+		"sql":   "'it''s a \"test\"\n\t\\ <x> & 1,2 $5.00\x01'",
+		"xml":   "\"it&apos;s a &quot;test&quot;&#10;&#9;\\ &lt;x&gt; &amp; 1,2 $5.00\x01\"",
+		"csv":   "\"it's a \"\"test\"\"\n\t\\ <x> & 1,2 $5.00\x01\"",
+		"c":     `"it's a \"test\"\n\t\\ <x> & 1,2 $5.00\001"`,
+		"js":    `"it's a \"test\"\n\t\\ <x> & 1,2 $5.00\x01"`,
+	}
+	for _, target := range escape_targets {
+		t.Run(target.Name, func(t *testing.T) {
+			escaped, err := target.Escape(input)
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if escaped != cases[target.Name] {
+				t.Fatalf("Got %q expected %q", escaped, cases[target.Name])
+			}
+			unescaped, err := target.Unescape(escaped)
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if unescaped != input {
+				t.Fatalf("Got %q expected the input back", unescaped)
+			}
+		})
+	}
+}
+
+func TestUnescapeLiterals(t *testing.T) {
+	cases := []struct {
+		Target   string
+		Input    string
+		Expected string
+	}{
+		{"shell", `"double \"quoted\""`, `double "quoted"`},
+		{"shell", `a\ b'c'`, "a bc"},
+		{"go", "`raw \\n`", `raw \n`},
+		{"c", `"\0\12\x41é\U0001F600\?"`, "\x00\nAé😀?"},
+		{"js", `'\0\x41é\u{1F600}\''`, "\x00Aé😀'"},
+		{"js", `"\ud83d\ude00!"`, "😀!"},
+		{"c", `"\xff\377"`, "\xff\xff"},
+		{"xml", "'&lt;&#x41;&apos;'", "<A'"},
+		{"csv", `"a,b"`, "a,b"},
+	}
+	for _, c := range cases {
+		t.Run(c.Target+" "+c.Input, func(t *testing.T) {
+			target, _, _ := lookupEscapeTarget(c.Target)
+			result, err := target.Unescape(c.Input)
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if result != c.Expected {
+				t.Fatalf("Got %q expected %q", result, c.Expected)
+			}
+		})
+	}
+}
+
+func TestUnescapeErrors(t *testing.T) {
+	cases := []struct {
+		Target   string
+		Input    string
+		Expected string
+	}{
+		{"shell", "a b", "Expected a single shell word got 2"},
+		{"sql", "'it's'", "Single quotes in the string need to be doubled"},
+		{"regex", `a\d`, `\d at character 2 isn't an escaped character`},
+		{"csv", "a,b", "Expected a single CSV field got 2"},
+		{"c", `"\q"`, `Unknown escape \q at character 2`},
+		{"js", `"\u{12"`, `Missing } for \u{ at character 2`},
+		{"c", `"\xg"`, `Invalid \x escape at character 2`},
+		{"js", `"\x4"`, `Invalid \x escape at character 2, expected 2 hex digits`},
+		{"js", `"\x4g"`, `Invalid \x escape at character 2, expected 2 hex digits`},
+		{"c", `"\x141"`, `\x141 at character 2 is more than a byte`},
+		{"c", `"a\400"`, `\400 at character 3 is more than a byte`},
+		{"js", `"\ud83d"`, `Unpaired surrogate \ud83d at character 2`},
+		{"js", `"\ud83d\u0041"`, `Unpaired surrogate \ud83d at character 2`},
+		{"js", `"a\ude00"`, `Unpaired surrogate \ude00 at character 3`},
+		{"json", `'a'`, "Expected a JSON string"},
+	}
+	for _, c := range cases {
+		t.Run(c.Target+" "+c.Input, func(t *testing.T) {
+			target, _, _ := lookupEscapeTarget(c.Target)
+			_, err := target.Unescape(c.Input)
+			if err == nil || err.Error() != c.Expected {
+				t.Fatalf("Got %v expected %s", err, c.Expected)
+			}
+		})
+	}
+}
+
+func TestEscapeCommand(t *testing.T) {
+	t.Run("AllTargets", func(t *testing.T) {
+		items, err := runCommandQuery(t, escapeCommand, `say "hi"`, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items) != len(escape_targets) {
+			t.Fatalf("Got %d items expected one per target", len(items))
+		}
+		if items[0].Title != `'say "hi"'` || items[0].Subtitle != "POSIX shell single quoted" {
+			t.Fatalf("Got %s (%s)", items[0].Title, items[0].Subtitle)
+		}
+	})
+	t.Run("Target", func(t *testing.T) {
+		items, err := runCommandQuery(t, escapeCommand, `sql O'Brien`, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items) != 1 || items[0].Title != "'O''Brien'" {
+			t.Fatalf("Got %v", items)
+		}
+	})
+	t.Run("Unescape", func(t *testing.T) {
+		items, err := runCommandQuery(t, escapeCommand, `ungo "a\tb"`, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != "a\tb" || items[0].Subtitle != "Unescaped Go string literal" {
+			t.Fatalf("Got %q (%s)", items[0].Title, items[0].Subtitle)
+		}
+	})
+	t.Run("UnescapeAny", func(t *testing.T) {
+		items, err := runCommandQuery(t, escapeCommand, `un 'it''s'`, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		targets := []string{}
+		for _, item := range items {
+			targets = append(targets, item.UID)
+		}
+		if strings.Join(targets, " ") != "shell sql xml js" {
+			t.Fatalf("Got %v", targets)
+		}
+	})
+	t.Run("UnescapeUsage", func(t *testing.T) {
+		items, err := runCommandQuery(t, escapeCommand, "un", "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items) != len(escape_targets) {
+			t.Fatalf("Got %d items expected one per target", len(items))
+		}
+		for _, item := range items {
+			if !strings.HasPrefix(item.Title, "un") {
+				t.Fatalf("Got %s expected only unescape targets", item.Title)
+			}
+		}
+	})
+}
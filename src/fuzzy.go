@@ -0,0 +1,273 @@
+package ralphred
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores for the different ways a single search term can match. A term gets
+// the best score of the ways it matches
+const (
+	exactMatchScore     = 1000
+	prefixMatchScore    = 800
+	wordMatchScore      = 600
+	wordPrefixScore     = 500
+	boundaryChunksScore = 350
+	substringScore      = 300
+	typoScore           = 150
+)
+
+type fuzzyText struct {
+	lower []rune
+	// Indexes in lower where a new word starts. Words are split on anything
+	// that isn't a letter or number, camelCase humps and letter/digit changes
+	boundaries []bool
+	words      [][]rune
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+func newFuzzyText(text string) fuzzyText {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	boundaries := make([]bool, len(runes))
+	words := [][]rune{}
+	wordStart := -1
+
+	for i, char := range runes {
+		lower[i] = unicode.ToLower(char)
+		if !isWordRune(char) {
+			if wordStart != -1 {
+				words = append(words, lower[wordStart:i])
+				wordStart = -1
+			}
+			continue
+		}
+
+		isBoundary := i == 0 || !isWordRune(runes[i-1])
+		if !isBoundary {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			isBoundary = (unicode.IsLower(prev) && unicode.IsUpper(char)) ||
+				(unicode.IsUpper(prev) && unicode.IsUpper(char) && nextIsLower) ||
+				(unicode.IsDigit(prev) != unicode.IsDigit(char))
+		}
+		boundaries[i] = isBoundary
+
+		if isBoundary {
+			if wordStart != -1 {
+				words = append(words, lower[wordStart:i])
+			}
+			wordStart = i
+		}
+	}
+	if wordStart != -1 {
+		words = append(words, lower[wordStart:])
+	}
+
+	return fuzzyText{lower: lower, boundaries: boundaries, words: words}
+}
+
+func hasRunePrefix(runes []rune, prefix []rune) bool {
+	if len(prefix) > len(runes) {
+		return false
+	}
+	for i, char := range prefix {
+		if runes[i] != char {
+			return false
+		}
+	}
+	return true
+}
+
+func (text fuzzyText) isWordEnd(index int) bool {
+	return index == len(text.lower) || text.boundaries[index] || !isWordRune(text.lower[index])
+}
+
+func (text fuzzyText) substringScore(term []rune) int {
+	best := 0
+	for start := 0; start+len(term) <= len(text.lower); start++ {
+		if !hasRunePrefix(text.lower[start:], term) {
+			continue
+		}
+		score := substringScore
+		if text.boundaries[start] {
+			score = wordPrefixScore
+			if text.isWordEnd(start + len(term)) {
+				score = wordMatchScore
+			}
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// Check if the term can be split into chunks that each match the start of a
+// word in order, like "hsc" for "HTTPServerConfig"
+func (text fuzzyText) matchesBoundaryChunks(term []rune, textIndex int) bool {
+	if len(term) == 0 {
+		return true
+	}
+	for start := textIndex; start < len(text.lower); start++ {
+		if !text.boundaries[start] || text.lower[start] != term[0] {
+			continue
+		}
+		for length := 1; length <= len(term) && start+length <= len(text.lower); length++ {
+			if text.lower[start+length-1] != term[length-1] {
+				break
+			}
+			if text.matchesBoundaryChunks(term[length:], start+length) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Optimal string alignment distance, which is Levenshtein distance that also
+// counts swapping two adjacent characters as one edit
+func editDistance(a []rune, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			best := rows[i-1][j] + 1
+			if rows[i][j-1]+1 < best {
+				best = rows[i][j-1] + 1
+			}
+			if rows[i-1][j-1]+cost < best {
+				best = rows[i-1][j-1] + cost
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && rows[i-2][j-2]+1 < best {
+				best = rows[i-2][j-2] + 1
+			}
+			rows[i][j] = best
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+// Short terms don't allow typos since almost everything would match them
+func allowedTypos(term []rune) int {
+	if len(term) >= 8 {
+		return 2
+	} else if len(term) >= 4 {
+		return 1
+	}
+	return 0
+}
+
+func (text fuzzyText) typoScore(term []rune) int {
+	maxTypos := allowedTypos(term)
+	if maxTypos == 0 {
+		return 0
+	}
+
+	best := maxTypos + 1
+	for _, word := range text.words {
+		distance := editDistance(term, word)
+		// Also compare against the start of the word since the rest of it
+		// might not have been typed yet
+		if len(word) > len(term) {
+			prefixDistance := editDistance(term, word[:len(term)])
+			if prefixDistance < distance {
+				distance = prefixDistance
+			}
+		}
+		if distance < best {
+			best = distance
+		}
+	}
+
+	if best > maxTypos {
+		return 0
+	}
+	return typoScore - 25*best
+}
+
+func (text fuzzyText) termScore(term []rune) int {
+	if len(term) == len(text.lower) && hasRunePrefix(text.lower, term) {
+		return exactMatchScore
+	}
+	if hasRunePrefix(text.lower, term) {
+		return prefixMatchScore
+	}
+
+	score := text.substringScore(term)
+	if score < boundaryChunksScore && text.matchesBoundaryChunks(term, 0) {
+		score = boundaryChunksScore
+	}
+	if score == 0 {
+		score = text.typoScore(term)
+	}
+	return score
+}
+
+// Score how well the text matches the search terms. Every term has to match
+// for the text to match, and a higher score is a better match
+func fuzzyScore(text string, terms []string) (int, bool) {
+	fuzzy := newFuzzyText(text)
+	total := 0
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		score := fuzzy.termScore([]rune(strings.ToLower(term)))
+		if score == 0 {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// Match and rank count things by the text returned for each index. The
+// indexes of the matches are returned best first. Matches with the same
+// score are ordered by shortest text and then by the original order
+func fuzzyRank(count int, text func(int) string, terms []string) []int {
+	type rankedMatch struct {
+		index  int
+		score  int
+		length int
+	}
+
+	matches := []rankedMatch{}
+	for i := 0; i < count; i++ {
+		itemText := text(i)
+		score, matched := fuzzyScore(itemText, terms)
+		if matched {
+			matches = append(matches, rankedMatch{index: i, score: score, length: len(itemText)})
+		}
+	}
+
+	if len(terms) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].score != matches[j].score {
+				return matches[i].score > matches[j].score
+			}
+			return matches[i].length < matches[j].length
+		})
+	}
+
+	indexes := make([]int, len(matches))
+	for i, match := range matches {
+		indexes[i] = match.index
+	}
+	return indexes
+}
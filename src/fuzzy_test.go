@@ -0,0 +1,76 @@
+package ralphred
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	assertMatch := func(t *testing.T, text string, term string, matches bool) {
+		t.Helper()
+		_, result := fuzzyScore(text, []string{term})
+		if result != matches {
+			t.Fatalf("Matching %s against %s got %t expected %t", term, text, result, matches)
+		}
+	}
+	assertBetter := func(t *testing.T, term string, better string, worse string) {
+		t.Helper()
+		betterScore, _ := fuzzyScore(better, []string{term})
+		worseScore, _ := fuzzyScore(worse, []string{term})
+		if betterScore <= worseScore {
+			t.Fatalf("Expected %s (%d) to score higher than %s (%d) for %s", better, betterScore, worse, worseScore, term)
+		}
+	}
+	t.Run("CaseInsensitive", func(t *testing.T) {
+		assertMatch(t, "ArrayBuffer", "arraybuffer", true)
+	})
+	t.Run("CamelCaseChunks", func(t *testing.T) {
+		assertMatch(t, "HTTPServer2Config", "hsc", true)
+	})
+	t.Run("SeparatorChunks", func(t *testing.T) {
+		assertMatch(t, "std::vector::push_back", "vecpb", true)
+	})
+	t.Run("NoChunksMidWord", func(t *testing.T) {
+		assertMatch(t, "ArrayBuffer", "rb", false)
+	})
+	t.Run("SingleTypo", func(t *testing.T) {
+		assertMatch(t, "Array.prototype.filter", "fitler", true)
+	})
+	t.Run("TypoWhileTyping", func(t *testing.T) {
+		assertMatch(t, "Array.prototype.filter", "protp", true)
+	})
+	t.Run("NoTyposForShortTerms", func(t *testing.T) {
+		assertMatch(t, "map", "mpa", false)
+	})
+	t.Run("ExactBeatsPrefix", func(t *testing.T) {
+		assertBetter(t, "map", "Map", "Map.prototype.get")
+	})
+	t.Run("PrefixBeatsWord", func(t *testing.T) {
+		assertBetter(t, "map", "Map.prototype.get", "Array.prototype.map")
+	})
+	t.Run("WordBeatsSubstring", func(t *testing.T) {
+		assertBetter(t, "map", "Array.prototype.map", "Array.prototype.flatmap")
+	})
+	t.Run("SubstringBeatsTypo", func(t *testing.T) {
+		assertBetter(t, "filter", "unfiltered", "fitler")
+	})
+}
+
+func TestFuzzyRank(t *testing.T) {
+	texts := []string{"Array.prototype.flatMap", "Array.prototype.map", "Map", "Set"}
+	text := func(i int) string {
+		return texts[i]
+	}
+	t.Run("SortedByScore", func(t *testing.T) {
+		result := fuzzyRank(len(texts), text, []string{"map"})
+		if !reflect.DeepEqual(result, []int{2, 1, 0}) {
+			t.Fatalf("Got %v expected [2 1 0]", result)
+		}
+	})
+	t.Run("NoTermsKeepsOrder", func(t *testing.T) {
+		result := fuzzyRank(len(texts), text, []string{})
+		if !reflect.DeepEqual(result, []int{0, 1, 2, 3}) {
+			t.Fatalf("Got %v expected [0 1 2 3]", result)
+		}
+	})
+}
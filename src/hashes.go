@@ -0,0 +1,252 @@
+package ralphred
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type hashAlgorithm struct {
+	Name string
+	New  func() hash.Hash
+	// Checksums aren't meant for HMAC
+	Cryptographic bool
+}
+
+var hash_algorithms = []hashAlgorithm{
+	{Name: "md5", New: md5.New, Cryptographic: true},
+	{Name: "sha1", New: sha1.New, Cryptographic: true},
+	{Name: "sha224", New: sha256.New224, Cryptographic: true},
+	{Name: "sha256", New: sha256.New, Cryptographic: true},
+	{Name: "sha384", New: sha512.New384, Cryptographic: true},
+	{Name: "sha512", New: sha512.New, Cryptographic: true},
+	{Name: "sha512_256", New: sha512.New512_256, Cryptographic: true},
+	{Name: "crc32", New: func() hash.Hash { return crc32.NewIEEE() }},
+	{Name: "crc32c", New: func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }},
+	{Name: "adler32", New: func() hash.Hash { return adler32.New() }},
+	{Name: "fnv32", New: func() hash.Hash { return fnv.New32() }},
+	{Name: "fnv32a", New: func() hash.Hash { return fnv.New32a() }},
+	{Name: "fnv64", New: func() hash.Hash { return fnv.New64() }},
+	{Name: "fnv64a", New: func() hash.Hash { return fnv.New64a() }},
+}
+
+var hash_conversions = func() map[string]StringConversion {
+	conversions := map[string]StringConversion{}
+	for _, algorithm := range hash_algorithms {
+		algorithm := algorithm
+		conversions[algorithm.Name] = StringConversion{
+			Description: fmt.Sprintf("Return %s hash of the string", algorithm.Name),
+			Convert: func(input_string string) (string, error) {
+				return hashString(algorithm.New(), input_string), nil
+			},
+		}
+	}
+	return conversions
+}()
+
+type hashResult struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// Hash the input with every algorithm at once, so a file is only read once
+// and never held in memory
+func hashReader(reader io.Reader, hashers map[string]hash.Hash, names []string) ([]hashResult, error) {
+	writers := make([]io.Writer, len(names))
+	for i, name := range names {
+		writers[i] = hashers[name]
+	}
+	_, err := io.Copy(io.MultiWriter(writers...), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]hashResult, len(names))
+	for i, name := range names {
+		results[i] = hashResult{Algorithm: name, Sum: hashers[name].Sum(nil)}
+	}
+	return results, nil
+}
+
+// With a key each cryptographic hash is used as an HMAC instead
+func hashAll(reader io.Reader, hmacKey *string) ([]hashResult, error) {
+	hashers := map[string]hash.Hash{}
+	names := []string{}
+	for _, algorithm := range hash_algorithms {
+		if hmacKey == nil {
+			hashers[algorithm.Name] = algorithm.New()
+		} else if algorithm.Cryptographic {
+			hashers[algorithm.Name] = hmac.New(algorithm.New, []byte(*hmacKey))
+		} else {
+			continue
+		}
+		names = append(names, algorithm.Name)
+	}
+	return hashReader(reader, hashers, names)
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
+func hashFile(path string, hmacKey *string) ([]hashResult, error) {
+	path, err := expandHome(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	return hashAll(file, hmacKey)
+}
+
+// The hex is the title and arg, holding alt gives the base64 instead
+func hashResultItems(results []hashResult, prefix string) []AlfredItem {
+	items := make([]AlfredItem, len(results))
+	for i, result := range results {
+		hexSum := hex.EncodeToString(result.Sum)
+		base64Sum := base64.StdEncoding.EncodeToString(result.Sum)
+		items[i] = alfredItemFromString(hexSum, false).
+			withSubtitle(fmt.Sprintf("%s%s, base64 %s", prefix, result.Algorithm, base64Sum)).
+			withMatch(result.Algorithm).
+			withMod(ModAlt, AlfredMod{
+				Arg:      []string{base64Sum},
+				Subtitle: fmt.Sprintf("Copy the base64 %s%s", prefix, result.Algorithm),
+			})
+		items[i].UID = prefix + result.Algorithm
+	}
+	return items
+}
+
+// Checksums are usually hex, but some tools give base64
+func decodeChecksum(checksum string) ([][]byte, error) {
+	checksum = strings.TrimSpace(checksum)
+	candidates := [][]byte{}
+	if decoded, err := hex.DecodeString(checksum); err == nil {
+		candidates = append(candidates, decoded)
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(checksum); err == nil {
+			candidates = append(candidates, decoded)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%s isn't a hex or base64 checksum", checksum)
+	}
+	return candidates, nil
+}
+
+func verifyChecksum(checksum string, results []hashResult, prefix string) ([]AlfredItem, error) {
+	candidates, err := decodeChecksum(checksum)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	for _, result := range results {
+		for _, candidate := range candidates {
+			if hmac.Equal(candidate, result.Sum) {
+				return []AlfredItem{
+					alfredItemFromString(fmt.Sprintf("Matches %s%s", prefix, result.Algorithm), false).
+						withSubtitle(hex.EncodeToString(result.Sum)).
+						withValid(false),
+				}, nil
+			}
+		}
+	}
+	return []AlfredItem{}, errors.New("Checksum doesn't match any of the algorithms")
+}
+
+var hash_usage = []AlfredItem{
+	alfredItemFromString("hash <text>", false).withSubtitle("Hash the text with every algorithm").withValid(false),
+	alfredItemFromString("hash file <path>", false).withSubtitle("Hash the contents of a file").withValid(false),
+	alfredItemFromString("hash hmac <key> <text>", false).withSubtitle("HMAC the text with a key").withValid(false),
+	alfredItemFromString("hash verify <checksum> [hmac <key>] [file <path> | <text>]", false).withSubtitle("Find the algorithm a checksum or HMAC was made with").withValid(false),
+}
+
+// Results for the text or file after the first n args
+func hashInput(req CommandRequest, n int, hmacKey *string) ([]hashResult, error) {
+	if len(req.Args) > n && req.Args[n] == "file" {
+		return hashFile(req.RawAfter(n+1), hmacKey)
+	}
+	return hashAll(strings.NewReader(req.RawAfter(n)), hmacKey)
+}
+
+func hashCommand(req CommandRequest) ([]AlfredItem, error) {
+	args := req.Args
+	if len(args) == 0 {
+		return hash_usage, nil
+	}
+
+	switch args[0] {
+	case "hmac":
+		if len(args) < 3 {
+			return hash_usage[2:3], nil
+		}
+		key := args[1]
+		results, err := hashAll(strings.NewReader(req.RawAfter(2)), &key)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return hashResultItems(results, "hmac-"), nil
+	case "verify":
+		if len(args) < 3 {
+			return hash_usage[3:], nil
+		}
+		// An HMAC is checked against the HMAC of every cryptographic hash
+		inputStart := 2
+		var key *string
+		prefix := ""
+		if args[2] == "hmac" {
+			if len(args) < 5 {
+				return hash_usage[3:], nil
+			}
+			key = &args[3]
+			inputStart = 4
+			prefix = "hmac-"
+		}
+		results, err := hashInput(req, inputStart, key)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return verifyChecksum(args[1], results, prefix)
+	default:
+		if args[0] == "file" && len(args) == 1 {
+			return hash_usage[1:2], nil
+		}
+		results, err := hashInput(req, 0, nil)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return hashResultItems(results, ""), nil
+	}
+}
@@ -0,0 +1,136 @@
+package ralphred
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func hashItemsByAlgorithm(items []AlfredItem) map[string]string {
+	sums := map[string]string{}
+	for _, item := range items {
+		sums[item.UID] = item.Title
+	}
+	return sums
+}
+
+func TestHashAlgorithms(t *testing.T) {
+	items, err := runCommandQuery(t, hashCommand, "hello", "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	sums := hashItemsByAlgorithm(items)
+	expected := map[string]string{
+		"sha512_256": "e30d87cfa2a75db545eac4d61baf970366a8357c7f72fa95b52d0accb698f13a",
+		"crc32":      "3610a686",
+		"crc32c":     "9a71bb4c",
+		"adler32":    "062c0215",
+		"fnv32":      "b6fa7167",
+		"fnv32a":     "4f9f2cab",
+		"fnv64":      "7b495389bdbdd4c7",
+		"fnv64a":     "a430d84680aabd0b",
+	}
+	for algorithm, sum := range expected {
+		if sums[algorithm] != sum {
+			t.Fatalf("Got %s expected %s for %s", sums[algorithm], sum, algorithm)
+		}
+	}
+	t.Run("Base64", func(t *testing.T) {
+		items, err := runCommandQuery(t, hashCommand, "hello", "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		for _, item := range items {
+			if item.UID == "sha256" && item.Mods[ModAlt].Arg[0] != "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+				t.Fatalf("Got %s expected the base64 sha256", item.Mods[ModAlt].Arg[0])
+			}
+		}
+	})
+	t.Run("StringConversion", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"crc32", "hello"}, "3610a686")
+	})
+}
+
+func TestHMAC(t *testing.T) {
+	items, err := runCommandQuery(t, hashCommand, "hmac key hello", "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	sums := hashItemsByAlgorithm(items)
+	if sums["hmac-sha256"] != "9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b" {
+		t.Fatalf("Got %s expected the sha256 hmac", sums["hmac-sha256"])
+	}
+	if _, exists := sums["hmac-crc32"]; exists {
+		t.Fatalf("Checksums shouldn't be used for HMAC")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file name.txt")
+	err := os.WriteFile(path, []byte("hello"), 0600)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	items, err := runCommandQuery(t, hashCommand, "file "+path, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	sums := hashItemsByAlgorithm(items)
+	if sums["crc32"] != "3610a686" {
+		t.Fatalf("Got %s expected the crc32 of the file", sums["crc32"])
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		items, err := runCommandQuery(t, hashCommand, "verify 062C0215 file "+path, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != "Matches adler32" {
+			t.Fatalf("Got %s expected adler32", items[0].Title)
+		}
+	})
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	items, err := runCommandQuery(t, hashCommand, "verify LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ= hello", "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "Matches sha256" {
+		t.Fatalf("Got %s expected sha256", items[0].Title)
+	}
+	_, err = runCommandQuery(t, hashCommand, "verify 00000000 hello", "")
+	if err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Fatalf("Got %v expected no match", err)
+	}
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	items, err := runCommandQuery(t, hashCommand, "verify 9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b hmac key hello", "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "Matches hmac-sha256" {
+		t.Fatalf("Got %s expected hmac-sha256", items[0].Title)
+	}
+	_, err = runCommandQuery(t, hashCommand, "verify 9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b hmac other hello", "")
+	if err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Fatalf("Got %v expected no match with the wrong key", err)
+	}
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		err := os.WriteFile(path, []byte("hello"), 0600)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		items, err := runCommandQuery(t, hashCommand, "verify 9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b hmac key file "+path, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != "Matches hmac-sha256" {
+			t.Fatalf("Got %s expected hmac-sha256", items[0].Title)
+		}
+	})
+}
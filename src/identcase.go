@@ -0,0 +1,266 @@
+package ralphred
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type identifierCase struct {
+	Name    string
+	Example string
+	Join    func(words []string) string
+}
+
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func joinWords(words []string, separator string, transform func(int, string) string) string {
+	converted := make([]string, len(words))
+	for i, word := range words {
+		converted[i] = transform(i, word)
+	}
+	return strings.Join(converted, separator)
+}
+
+func lowerWord(_ int, word string) string {
+	return strings.ToLower(word)
+}
+
+func upperWord(_ int, word string) string {
+	return strings.ToUpper(word)
+}
+
+func capitalizedWord(_ int, word string) string {
+	return capitalize(word)
+}
+
+// In the order they're shown when no target case is given
+var identifier_cases = []identifierCase{
+	{
+		Name:    "camel",
+		Example: "camelCase",
+		Join: func(words []string) string {
+			return joinWords(words, "", func(i int, word string) string {
+				if i == 0 {
+					return strings.ToLower(word)
+				}
+				return capitalize(word)
+			})
+		},
+	},
+	{
+		Name:    "pascal",
+		Example: "PascalCase",
+		Join: func(words []string) string {
+			return joinWords(words, "", capitalizedWord)
+		},
+	},
+	{
+		Name:    "snake",
+		Example: "snake_case",
+		Join: func(words []string) string {
+			return joinWords(words, "_", lowerWord)
+		},
+	},
+	{
+		Name:    "screaming",
+		Example: "SCREAMING_SNAKE",
+		Join: func(words []string) string {
+			return joinWords(words, "_", upperWord)
+		},
+	},
+	{
+		Name:    "kebab",
+		Example: "kebab-case",
+		Join: func(words []string) string {
+			return joinWords(words, "-", lowerWord)
+		},
+	},
+	{
+		Name:    "train",
+		Example: "Train-Case",
+		Join: func(words []string) string {
+			return joinWords(words, "-", capitalizedWord)
+		},
+	},
+	{
+		Name:    "dot",
+		Example: "dot.case",
+		Join: func(words []string) string {
+			return joinWords(words, ".", lowerWord)
+		},
+	},
+	{
+		Name:    "space",
+		Example: "space separated",
+		Join: func(words []string) string {
+			return joinWords(words, " ", lowerWord)
+		},
+	},
+}
+
+func lookupIdentifierCase(name string) (identifierCase, bool) {
+	for _, identCase := range identifier_cases {
+		if identCase.Name == name {
+			return identCase, true
+		}
+	}
+	return identifierCase{}, false
+}
+
+func isIdentifierSeparator(char rune) bool {
+	return char == '_' || char == '-' || char == '.' || unicode.IsSpace(char)
+}
+
+// Split an identifier into words. Besides separators, a word starts at a
+// lower to upper change and at the last capital of an acronym, so
+// HTTPServer2Config is HTTP, Server2 and Config. Digits stay with the word
+// before them
+func splitIdentifier(identifier string) []string {
+	words := []string{}
+	for _, chunk := range strings.FieldsFunc(identifier, isIdentifierSeparator) {
+		runes := []rune(chunk)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			previous, current := runes[i-1], runes[i]
+			startsWord := false
+			if unicode.IsUpper(current) {
+				if unicode.IsLower(previous) || unicode.IsDigit(previous) {
+					startsWord = true
+				} else if unicode.IsUpper(previous) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+					startsWord = true
+				}
+			}
+			if startsWord {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// Detect the case of an identifier, returns an empty string for a single
+// lower case word since it could be any of the lower case styles
+func detectIdentifierCase(identifier string) string {
+	hasUpper := strings.ToLower(identifier) != identifier
+	hasLower := strings.ToUpper(identifier) != identifier
+	words := splitIdentifier(identifier)
+	allCapitalized := true
+	for _, word := range words {
+		if word != capitalize(word) {
+			allCapitalized = false
+		}
+	}
+
+	switch {
+	case strings.IndexFunc(identifier, unicode.IsSpace) != -1:
+		return "space"
+	case strings.Contains(identifier, "_"):
+		if hasUpper && !hasLower {
+			return "screaming"
+		}
+		return "snake"
+	case strings.Contains(identifier, "-"):
+		if allCapitalized {
+			return "train"
+		}
+		return "kebab"
+	case strings.Contains(identifier, "."):
+		return "dot"
+	case !hasUpper:
+		return ""
+	case unicode.IsUpper([]rune(identifier)[0]):
+		return "pascal"
+	default:
+		return "camel"
+	}
+}
+
+func caseItem(identCase identifierCase, result string, detected string) AlfredItem {
+	subtitle := identCase.Example
+	if detected != "" {
+		from, _ := lookupIdentifierCase(detected)
+		subtitle = fmt.Sprintf("%s (from %s)", identCase.Example, from.Example)
+	}
+	item := alfredItemFromString(result, false).withSubtitle(subtitle)
+	item.UID = identCase.Name
+	return item
+}
+
+func caseCommands(searchQuery []string) []AlfredItem {
+	items := make([]AlfredItem, len(identifier_cases))
+	for i, identCase := range identifier_cases {
+		items[i] = AlfredItem{
+			UID:          identCase.Name,
+			Title:        identCase.Name,
+			Subtitle:     fmt.Sprintf("Convert an identifier to %s", identCase.Example),
+			Arg:          []string{identCase.Name + " "},
+			Autocomplete: identCase.Name,
+		}
+	}
+	return filterAlfredItems(items, searchQuery)
+}
+
+// Convert an identifier to the given case, or to every other case if the
+// first arg isn't the name of a case
+func caseCommand(args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return caseCommands([]string{}), nil
+	}
+
+	identCase, exists := lookupIdentifierCase(args[0])
+	if exists && len(args) == 1 {
+		return caseCommands(args), nil
+	} else if exists {
+		identifier := strings.Join(args[1:], " ")
+		words := splitIdentifier(identifier)
+		return []AlfredItem{
+			caseItem(identCase, identCase.Join(words), detectIdentifierCase(identifier)),
+		}, nil
+	}
+
+	identifier := strings.Join(args, " ")
+	words := splitIdentifier(identifier)
+	if len(words) == 0 {
+		return caseCommands([]string{}), nil
+	}
+	detected := detectIdentifierCase(identifier)
+	items := []AlfredItem{}
+	// Cases can give the same result, like Pascal and Train for one word
+	seen := map[string]bool{}
+	for _, identCase := range identifier_cases {
+		result := identCase.Join(words)
+		if identCase.Name == detected || result == identifier || seen[result] {
+			continue
+		}
+		seen[result] = true
+		items = append(items, caseItem(identCase, result, detected))
+	}
+	return items, nil
+}
+
+func identifierCaseConversion(identCase identifierCase) StringConversion {
+	return StringConversion{
+		Description: fmt.Sprintf("Convert an identifier to %s", identCase.Example),
+		Convert: func(input_string string) (string, error) {
+			return identCase.Join(splitIdentifier(input_string)), nil
+		},
+	}
+}
+
+var case_conversions = func() map[string]StringConversion {
+	conversions := map[string]StringConversion{}
+	for _, identCase := range identifier_cases {
+		conversions[identCase.Name] = identifierCaseConversion(identCase)
+	}
+	return conversions
+}()
@@ -0,0 +1,96 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"HTTPServer2Config":  "HTTP Server2 Config",
+		"parseURL":           "parse URL",
+		"snake_case_name":    "snake case name",
+		"SCREAMING_SNAKE":    "SCREAMING SNAKE",
+		"kebab-case":         "kebab case",
+		"dot.case":           "dot case",
+		"Train-Case":         "Train Case",
+		"  space separated ": "space separated",
+		"version2":           "version2",
+	}
+	for input, expected := range cases {
+		t.Run(input, func(t *testing.T) {
+			result := strings.Join(splitIdentifier(input), " ")
+			if result != expected {
+				t.Fatalf("Got %s expected %s", result, expected)
+			}
+		})
+	}
+}
+
+func TestDetectIdentifierCase(t *testing.T) {
+	cases := map[string]string{
+		"httpServer":  "camel",
+		"HTTPServer":  "pascal",
+		"http_server": "snake",
+		"HTTP_SERVER": "screaming",
+		"http-server": "kebab",
+		"Http-Server": "train",
+		"http.server": "dot",
+		"http server": "space",
+		"server":      "",
+	}
+	for input, expected := range cases {
+		t.Run(input, func(t *testing.T) {
+			result := detectIdentifierCase(input)
+			if result != expected {
+				t.Fatalf("Got %s expected %s", result, expected)
+			}
+		})
+	}
+}
+
+func TestCaseCommand(t *testing.T) {
+	t.Run("Target", func(t *testing.T) {
+		items, err := caseCommand([]string{"snake", "HTTPServer2Config"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items) != 1 || items[0].Title != "http_server2_config" {
+			t.Fatalf("Got %v expected http_server2_config", items)
+		}
+	})
+	t.Run("AllOtherCases", func(t *testing.T) {
+		items, err := caseCommand([]string{"http_server"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		titles := []string{}
+		for _, item := range items {
+			titles = append(titles, item.Title)
+		}
+		expected := "httpServer HttpServer HTTP_SERVER http-server Http-Server http.server http server"
+		if strings.Join(titles, " ") != expected {
+			t.Fatalf("Got %s expected %s", strings.Join(titles, " "), expected)
+		}
+		if items[0].Subtitle != "camelCase (from snake_case)" {
+			t.Fatalf("Got %s expected camelCase (from snake_case)", items[0].Subtitle)
+		}
+	})
+	t.Run("NoDuplicates", func(t *testing.T) {
+		items, err := caseCommand([]string{"server"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		titles := []string{}
+		for _, item := range items {
+			titles = append(titles, item.Title)
+		}
+		expected := "Server SERVER"
+		if strings.Join(titles, " ") != expected {
+			t.Fatalf("Got %s expected %s", strings.Join(titles, " "), expected)
+		}
+	})
+	t.Run("StringConversion", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"camel", "http-server-2"}, "httpServer2")
+	})
+}
@@ -0,0 +1,359 @@
+package ralphred
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func randomBytes(count int) ([]byte, error) {
+	bytes := make([]byte, count)
+	_, err := rand.Read(bytes)
+	return bytes, err
+}
+
+func formatUUID(uuid []byte) string {
+	encoded := hex.EncodeToString(uuid)
+	return strings.Join([]string{encoded[:8], encoded[8:12], encoded[12:16], encoded[16:20], encoded[20:]}, "-")
+}
+
+// Set the version nibble and the RFC 4122 variant bits
+func setUUIDVersion(uuid []byte, version byte) {
+	uuid[6] = (uuid[6] & 0x0f) | version<<4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+}
+
+func newUUIDv4() (string, error) {
+	uuid, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	setUUIDVersion(uuid, 4)
+	return formatUUID(uuid), nil
+}
+
+// The first 48 bits are milliseconds since the epoch so they sort by time
+func newUUIDv7() (string, error) {
+	uuid, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	millis := uint64(now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		uuid[i] = byte(millis >> (40 - 8*i))
+	}
+	setUUIDVersion(uuid, 7)
+	return formatUUID(uuid), nil
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// 48 bits of milliseconds followed by 80 random bits, in Crockford's base32
+func newULID() (string, error) {
+	random, err := randomBytes(10)
+	if err != nil {
+		return "", err
+	}
+	value := new(big.Int).SetUint64(uint64(now().UnixMilli()))
+	value.Lsh(value, 80)
+	value.Or(value, new(big.Int).SetBytes(random))
+
+	encoded := make([]byte, 26)
+	base := big.NewInt(32)
+	digit := new(big.Int)
+	for i := len(encoded) - 1; i >= 0; i-- {
+		value.DivMod(value, base, digit)
+		encoded[i] = crockfordAlphabet[digit.Int64()]
+	}
+	return string(encoded), nil
+}
+
+const nanoIDAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// The alphabet has 64 characters, so each random byte is masked to 6 bits
+// and every character is equally likely
+func newNanoID(length int) (string, error) {
+	random, err := randomBytes(length)
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, length)
+	for i, b := range random {
+		id[i] = nanoIDAlphabet[b&63]
+	}
+	return string(id), nil
+}
+
+func newHexID(byteCount int) (string, error) {
+	random, err := randomBytes(byteCount)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+type idGenerator struct {
+	Description string
+	// Size is the length or byte count for generators that take one
+	DefaultSize int
+	Generate    func(size int) (string, error)
+}
+
+var id_generators = map[string]idGenerator{
+	"uuid4": {
+		Description: "Random UUID",
+		Generate: func(int) (string, error) {
+			return newUUIDv4()
+		},
+	},
+	"uuid7": {
+		Description: "Time ordered UUID",
+		Generate: func(int) (string, error) {
+			return newUUIDv7()
+		},
+	},
+	"ulid": {
+		Description: "Time ordered ULID",
+		Generate: func(int) (string, error) {
+			return newULID()
+		},
+	},
+	"nanoid": {
+		Description: "URL safe NanoID",
+		DefaultSize: 21,
+		Generate:    newNanoID,
+	},
+	"hex": {
+		Description: "Random hex",
+		DefaultSize: 16,
+		Generate:    newHexID,
+	},
+}
+
+// Generators are listed in this order when no id is given
+var id_generator_order = []string{"uuid4", "uuid7", "ulid", "nanoid", "hex"}
+
+// Larger sizes would be unwieldy in alfred
+const maxIDSize = 256
+
+func generateIDItems(names []string, size int) ([]AlfredItem, error) {
+	items := make([]AlfredItem, len(names))
+	for i, name := range names {
+		generator := id_generators[name]
+		generatorSize := size
+		if generatorSize == 0 {
+			generatorSize = generator.DefaultSize
+		}
+		id, err := generator.Generate(generatorSize)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		items[i] = alfredItemFromString(id, false).withSubtitle(generator.Description)
+		items[i].UID = name
+	}
+	return items, nil
+}
+
+type decodedID struct {
+	Format string
+	// Details about the ID shown in the subtitle, like the UUID version
+	Details []string
+	// Embedded timestamp, zero if the format doesn't have one
+	Time time.Time
+}
+
+var uuidRegex = regexp.MustCompile(`^(?i)(?:urn:uuid:)?\{?([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})\}?$`)
+var ulidRegex = regexp.MustCompile(`^(?i)[0-7][0-9a-hjkmnp-tv-z]{25}$`)
+var objectIDRegex = regexp.MustCompile(`^(?i)[0-9a-f]{24}$`)
+var snowflakeRegex = regexp.MustCompile(`^[0-9]{15,20}$`)
+
+// 100ns intervals between the gregorian epoch in UUIDs and the unix epoch
+const uuidGregorianOffset = 122192928000000000
+
+func uuidGregorianTime(intervals uint64) time.Time {
+	since := int64(intervals) - uuidGregorianOffset
+	return time.Unix(since/1e7, (since%1e7)*100)
+}
+
+func uuidVariant(uuid []byte) string {
+	switch {
+	case uuid[8]&0x80 == 0:
+		return "NCS"
+	case uuid[8]&0xc0 == 0x80:
+		return "RFC 4122"
+	case uuid[8]&0xe0 == 0xc0:
+		return "Microsoft"
+	default:
+		return "Future"
+	}
+}
+
+func decodeUUID(parts []string) decodedID {
+	uuid, _ := hex.DecodeString(strings.Join(parts, ""))
+	version := uuid[6] >> 4
+	decoded := decodedID{
+		Format:  "UUID",
+		Details: []string{fmt.Sprintf("version %d", version), uuidVariant(uuid) + " variant"},
+	}
+	if uuidVariant(uuid) != "RFC 4122" {
+		return decoded
+	}
+
+	switch version {
+	case 1:
+		timeLow := uint64(binary.BigEndian.Uint32(uuid[0:4]))
+		timeMid := uint64(binary.BigEndian.Uint16(uuid[4:6]))
+		timeHigh := uint64(binary.BigEndian.Uint16(uuid[6:8]) & 0x0fff)
+		decoded.Time = uuidGregorianTime(timeHigh<<48 | timeMid<<32 | timeLow)
+		decoded.Details = append(decoded.Details, "node "+hex.EncodeToString(uuid[10:]))
+	case 6:
+		timeHigh := uint64(binary.BigEndian.Uint32(uuid[0:4]))
+		timeMid := uint64(binary.BigEndian.Uint16(uuid[4:6]))
+		timeLow := uint64(binary.BigEndian.Uint16(uuid[6:8]) & 0x0fff)
+		decoded.Time = uuidGregorianTime(timeHigh<<28 | timeMid<<12 | timeLow)
+		decoded.Details = append(decoded.Details, "node "+hex.EncodeToString(uuid[10:]))
+	case 7:
+		millis := int64(binary.BigEndian.Uint64(append([]byte{0, 0}, uuid[0:6]...)))
+		decoded.Time = time.UnixMilli(millis)
+	}
+	return decoded
+}
+
+func decodeULID(ulid string) decodedID {
+	var millis int64
+	for _, char := range strings.ToUpper(ulid[:10]) {
+		millis = millis*32 + int64(strings.IndexRune(crockfordAlphabet, char))
+	}
+	return decodedID{Format: "ULID", Time: time.UnixMilli(millis)}
+}
+
+func decodeObjectID(objectID string) decodedID {
+	bytes, _ := hex.DecodeString(objectID)
+	seconds := int64(binary.BigEndian.Uint32(bytes[0:4]))
+	counter := uint32(bytes[9])<<16 | uint32(bytes[10])<<8 | uint32(bytes[11])
+	return decodedID{
+		Format:  "MongoDB ObjectId",
+		Details: []string{"random " + hex.EncodeToString(bytes[4:9]), fmt.Sprintf("counter %d", counter)},
+		Time:    time.Unix(seconds, 0),
+	}
+}
+
+// Epochs of the common snowflake formats in milliseconds
+var snowflake_epochs = map[string]int64{
+	"twitter": 1288834974657,
+	"discord": 1420070400000,
+}
+
+// Snowflakes are 41 bits of milliseconds since the epoch, 10 bits of
+// worker and 12 bits of sequence
+func decodeSnowflake(id string, epochName string) (decodedID, error) {
+	value, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return decodedID{}, fmt.Errorf("Invalid snowflake: %s", err)
+	}
+	epoch, exists := snowflake_epochs[epochName]
+	if !exists {
+		names := make([]string, 0, len(snowflake_epochs))
+		for name := range snowflake_epochs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return decodedID{}, fmt.Errorf("Unknown snowflake epoch \"%s\", expected one of %s", epochName, strings.Join(names, ", "))
+	}
+	return decodedID{
+		Format: fmt.Sprintf("Snowflake (%s epoch)", epochName),
+		Details: []string{
+			fmt.Sprintf("worker %d", (value>>12)&0x3ff),
+			fmt.Sprintf("sequence %d", value&0xfff),
+		},
+		Time: time.UnixMilli(int64(value>>22) + epoch),
+	}, nil
+}
+
+// Detect the format of an ID and decode what's in it. Hex that's the length
+// of an ObjectId could be anything, but ObjectIds are the common case
+func decodeID(id string, epochName string) (decodedID, error) {
+	switch {
+	case uuidRegex.MatchString(id):
+		return decodeUUID(uuidRegex.FindStringSubmatch(id)[1:]), nil
+	case ulidRegex.MatchString(id):
+		return decodeULID(id), nil
+	case objectIDRegex.MatchString(id):
+		return decodeObjectID(id), nil
+	case snowflakeRegex.MatchString(id):
+		return decodeSnowflake(id, epochName)
+	}
+	return decodedID{}, errors.New("Unknown ID format, expected a UUID, ULID, ObjectId or snowflake")
+}
+
+func idCommands(searchQuery []string) []AlfredItem {
+	items := make([]AlfredItem, len(id_generator_order))
+	for i, name := range id_generator_order {
+		items[i] = AlfredItem{
+			UID:          name,
+			Title:        name,
+			Subtitle:     fmt.Sprintf("Generate a %s", strings.ToLower(id_generators[name].Description)),
+			Arg:          []string{name},
+			Autocomplete: name,
+		}
+	}
+	return filterAlfredItems(items, searchQuery)
+}
+
+// Generate IDs, or decode an ID that's given. A generator can be followed by
+// a size for the ones that have one, and a snowflake by the name of its epoch
+func idCommand(config *Config, args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return generateIDItems(id_generator_order, 0)
+	}
+
+	if generator, exists := id_generators[args[0]]; exists {
+		size := 0
+		if len(args) > 1 {
+			if generator.DefaultSize == 0 {
+				return []AlfredItem{}, fmt.Errorf("%s doesn't take a size", args[0])
+			}
+			var err error
+			size, err = strconv.Atoi(args[1])
+			if err != nil || size <= 0 || size > maxIDSize {
+				return []AlfredItem{}, fmt.Errorf("Size must be a number from 1 to %d", maxIDSize)
+			}
+		}
+		return generateIDItems([]string{args[0]}, size)
+	}
+
+	epochName := "twitter"
+	if len(args) > 1 {
+		epochName = strings.ToLower(args[1])
+	}
+	decoded, err := decodeID(args[0], epochName)
+	if err != nil {
+		// A partially typed generator name
+		matching := idCommands(args[:1])
+		if len(args) == 1 && len(matching) > 0 {
+			return matching, nil
+		}
+		return []AlfredItem{}, err
+	}
+
+	info := alfredItemFromString(decoded.Format, false).
+		withSubtitle(strings.Join(decoded.Details, ", ")).
+		withValid(false)
+	info.Arg = []string{}
+	items := []AlfredItem{info}
+	if decoded.Time.IsZero() {
+		return items, nil
+	}
+	for _, item := range timeFormatItems(config, decoded.Time) {
+		items = append(items, item.withSubtitle(fmt.Sprintf("Embedded timestamp (%s)", item.UID)))
+	}
+	return items, nil
+}
@@ -0,0 +1,104 @@
+package ralphred
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateIDs(t *testing.T) {
+	formats := map[string]*regexp.Regexp{
+		"uuid4":  regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		"uuid7":  regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		"ulid":   regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`),
+		"nanoid": regexp.MustCompile(`^[A-Za-z0-9_-]{21}$`),
+		"hex":    regexp.MustCompile(`^[0-9a-f]{32}$`),
+	}
+	items, err := idCommand(defaultConfig(), []string{})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if len(items) != len(formats) {
+		t.Fatalf("Got %d items expected %d", len(items), len(formats))
+	}
+	for _, item := range items {
+		if !formats[item.UID].MatchString(item.Title) {
+			t.Fatalf("%s isn't a valid %s", item.Title, item.UID)
+		}
+	}
+
+	t.Run("Size", func(t *testing.T) {
+		items, err := idCommand(defaultConfig(), []string{"nanoid", "8"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items[0].Title) != 8 {
+			t.Fatalf("Got %s expected 8 characters", items[0].Title)
+		}
+	})
+	t.Run("TimeOrdered", func(t *testing.T) {
+		useFrozenNow(t, time.UnixMilli(1663782330123))
+		for _, generator := range []string{"uuid7", "ulid"} {
+			id, _ := id_generators[generator].Generate(0)
+			decoded, err := decodeID(id, "twitter")
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if decoded.Time.UnixMilli() != 1663782330123 {
+				t.Fatalf("Got %s expected the frozen time in %s", decoded.Time, id)
+			}
+		}
+	})
+}
+
+func TestDecodeID(t *testing.T) {
+	cases := []struct {
+		ID      string
+		Format  string
+		Details string
+		Time    string
+	}{
+		{"c232ab00-9414-11ec-b3c8-9f6bdeced846", "UUID", "version 1, RFC 4122 variant, node 9f6bdeced846", "2022-02-22T19:22:22Z"},
+		{"1EC9414C-232A-6B00-B3C8-9F6BDECED846", "UUID", "version 6, RFC 4122 variant, node 9f6bdeced846", "2022-02-22T19:22:22Z"},
+		{"017f22e2-79b0-7cc3-98c4-dc0c0c07398f", "UUID", "version 7, RFC 4122 variant", "2022-02-22T19:22:22Z"},
+		{"919108f7-52d1-4320-9bac-f847db4148a8", "UUID", "version 4, RFC 4122 variant", ""},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "ULID", "", "2016-07-30T23:54:10.259Z"},
+		{"507f1f77bcf86cd799439011", "MongoDB ObjectId", "random bcf86cd799, counter 4427793", "2012-10-17T21:13:27Z"},
+		{"1541815603606036480", "Snowflake (twitter epoch)", "worker 378, sequence 0", "2022-06-28T16:07:40.105Z"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.ID, func(t *testing.T) {
+			decoded, err := decodeID(testCase.ID, "twitter")
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if decoded.Format != testCase.Format {
+				t.Fatalf("Got %s expected %s", decoded.Format, testCase.Format)
+			}
+			if details := strings.Join(decoded.Details, ", "); details != testCase.Details {
+				t.Fatalf("Got %s expected %s", details, testCase.Details)
+			}
+			decodedTime := ""
+			if !decoded.Time.IsZero() {
+				decodedTime = decoded.Time.UTC().Format(time.RFC3339Nano)
+			}
+			if decodedTime != testCase.Time {
+				t.Fatalf("Got %s expected %s", decodedTime, testCase.Time)
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := idCommand(defaultConfig(), []string{"not-an-id", "x"})
+		if err == nil || !strings.Contains(err.Error(), "Unknown ID format") {
+			t.Fatalf("Got %v expected an unknown format error", err)
+		}
+	})
+	t.Run("PartialGenerator", func(t *testing.T) {
+		items, err := idCommand(defaultConfig(), []string{"uu"})
+		if err != nil || items[0].Title != "uuid4" {
+			t.Fatalf("Got %v, %v expected generators", items, err)
+		}
+	})
+}
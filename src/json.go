@@ -0,0 +1,648 @@
+package ralphred
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Larger indents are almost certainly a typo
+const maxJSONIndent = 16
+
+// Parse a JSON document, errors say the line and column the document became
+// invalid at
+func parseJSONDocument(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// The offset is just after the invalid character
+		line, column := lineAndColumn(data, syntaxErr.Offset-1)
+		return nil, fmt.Errorf("Invalid JSON at line %d column %d: %s", line, column, syntaxErr)
+	} else if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		line, column := lineAndColumn(data, int64(len(data)))
+		return nil, fmt.Errorf("Invalid JSON at line %d column %d: unexpected end of input", line, column)
+	} else if err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %s", err)
+	}
+
+	// Only whitespace is allowed after the document
+	offset := decoder.InputOffset()
+	rest := bytes.TrimLeft(data[offset:], " \t\r\n")
+	if len(rest) > 0 {
+		line, column := lineAndColumn(data, int64(len(data)-len(rest)))
+		return nil, fmt.Errorf("Invalid JSON at line %d column %d: unexpected data after the document", line, column)
+	}
+	return value, nil
+}
+
+func jsonIndent(spaces int) string {
+	if spaces == 0 {
+		return "\t"
+	}
+	return strings.Repeat(" ", spaces)
+}
+
+func describeIndent(spaces int) string {
+	if spaces == 0 {
+		return "tabs"
+	}
+	return pluralize(spaces, "space")
+}
+
+// Marshal without escaping <, > and & so the output can be pasted as is. An
+// empty indent gives compact output
+func marshalJSON(value interface{}, indent string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	err := encoder.Encode(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// Pretty print keeping the keys in the order they're in the document
+func prettyJSON(data []byte, indent string) (string, error) {
+	_, err := parseJSONDocument(data)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	err = json.Indent(&buffer, bytes.TrimSpace(data), "", indent)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func minifyJSON(data []byte) (string, error) {
+	_, err := parseJSONDocument(data)
+	if err != nil {
+		return "", err
+	}
+	var buffer bytes.Buffer
+	err = json.Compact(&buffer, data)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// Objects are decoded into maps, which are marshalled with sorted keys
+func sortedJSON(data []byte, indent string) (string, error) {
+	value, err := parseJSONDocument(data)
+	if err != nil {
+		return "", err
+	}
+	return marshalJSON(value, indent)
+}
+
+func describeJSONValue(value interface{}) string {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return fmt.Sprintf("object with %s", pluralize(len(typed), "key"))
+	case []interface{}:
+		return fmt.Sprintf("array with %s", pluralize(len(typed), "item"))
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+type jsonPathStepKind int
+
+const (
+	jsonPathKey jsonPathStepKind = iota
+	jsonPathIndex
+	jsonPathSlice
+	jsonPathIterate
+	jsonPathRecurse
+)
+
+type jsonPathStep struct {
+	Kind  jsonPathStepKind
+	Key   string
+	Index int
+	// Slice bounds, nil when left out
+	Start *int
+	End   *int
+}
+
+var jsonPathIdentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+func jsonPathError(position int, format string, v ...interface{}) error {
+	return fmt.Errorf("Invalid path at character %d: %s", position+1, fmt.Sprintf(format, v...))
+}
+
+func parseJSONPathBracket(content string, position int) (jsonPathStep, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return jsonPathStep{Kind: jsonPathIterate}, nil
+	}
+	if strings.HasPrefix(content, "\"") {
+		key, err := strconv.Unquote(content)
+		if err != nil {
+			return jsonPathStep{}, jsonPathError(position, "invalid key %s", content)
+		}
+		return jsonPathStep{Kind: jsonPathKey, Key: key}, nil
+	}
+
+	parseBound := func(bound string) (*int, error) {
+		bound = strings.TrimSpace(bound)
+		if bound == "" {
+			return nil, nil
+		}
+		number, err := strconv.Atoi(bound)
+		if err != nil {
+			return nil, jsonPathError(position, "%q isn't an index", bound)
+		}
+		return &number, nil
+	}
+
+	if strings.Contains(content, ":") {
+		bounds := strings.SplitN(content, ":", 2)
+		start, err := parseBound(bounds[0])
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		end, err := parseBound(bounds[1])
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{Kind: jsonPathSlice, Start: start, End: end}, nil
+	}
+
+	index, err := parseBound(content)
+	if err != nil {
+		return jsonPathStep{}, err
+	}
+	return jsonPathStep{Kind: jsonPathIndex, Index: *index}, nil
+}
+
+// Index of the ] that closes the bracket at the start of rest, skipping over
+// quoted keys since they can contain ]
+func jsonPathBracketEnd(rest string) int {
+	quoted := false
+	for i := 1; i < len(rest); i++ {
+		switch {
+		case quoted && rest[i] == '\\':
+			i++
+		case rest[i] == '"':
+			quoted = !quoted
+		case !quoted && rest[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// Parse a jq style path. Supports .key, ."key", .["key"], [n], [start:end],
+// [] to iterate and .. to recurse, so ..id is every id in the document
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, ".") {
+		return nil, jsonPathError(0, "paths start with .")
+	}
+
+	steps := []jsonPathStep{}
+	position := 0
+	for position < len(path) {
+		rest := path[position:]
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			steps = append(steps, jsonPathStep{Kind: jsonPathRecurse})
+			position += 2
+			if ident := jsonPathIdentRegex.FindString(path[position:]); ident != "" {
+				steps = append(steps, jsonPathStep{Kind: jsonPathKey, Key: ident})
+				position += len(ident)
+			}
+		case rest[0] == '.':
+			position++
+			after := path[position:]
+			if ident := jsonPathIdentRegex.FindString(after); ident != "" {
+				steps = append(steps, jsonPathStep{Kind: jsonPathKey, Key: ident})
+				position += len(ident)
+			} else if strings.HasPrefix(after, "\"") {
+				end := strings.Index(after[1:], "\"")
+				if end == -1 {
+					return nil, jsonPathError(position, "unterminated key")
+				}
+				steps = append(steps, jsonPathStep{Kind: jsonPathKey, Key: after[1 : end+1]})
+				position += end + 2
+			} else if after != "" && after[0] != '[' {
+				return nil, jsonPathError(position, "unexpected %q", after[0])
+			}
+		case rest[0] == '[':
+			end := jsonPathBracketEnd(rest)
+			if end == -1 {
+				return nil, jsonPathError(position, "missing ]")
+			}
+			step, err := parseJSONPathBracket(rest[1:end], position)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			position += end + 1
+		default:
+			return nil, jsonPathError(position, "unexpected %q", rest[0])
+		}
+	}
+	return steps, nil
+}
+
+type jsonMatch struct {
+	Path  string
+	Value interface{}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonKeyPath(path string, key string) string {
+	if key != "" && jsonPathIdentRegex.FindString(key) == key {
+		return path + "." + key
+	}
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+}
+
+// Every value in the document, parents before their children
+func jsonDescendants(match jsonMatch) []jsonMatch {
+	matches := []jsonMatch{match}
+	switch typed := match.Value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(typed) {
+			matches = append(matches, jsonDescendants(jsonMatch{jsonKeyPath(match.Path, key), typed[key]})...)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			matches = append(matches, jsonDescendants(jsonMatch{fmt.Sprintf("%s[%d]", match.Path, i), item})...)
+		}
+	}
+	return matches
+}
+
+// Clamp slice bounds the way python and jq do
+func sliceBound(bound *int, length int, fallback int) int {
+	if bound == nil {
+		return fallback
+	}
+	index := *bound
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	} else if index > length {
+		return length
+	}
+	return index
+}
+
+func applyJSONPathStep(step jsonPathStep, match jsonMatch) []jsonMatch {
+	switch step.Kind {
+	case jsonPathKey:
+		if object, ok := match.Value.(map[string]interface{}); ok {
+			if value, exists := object[step.Key]; exists {
+				return []jsonMatch{{jsonKeyPath(match.Path, step.Key), value}}
+			}
+		}
+	case jsonPathIndex:
+		if array, ok := match.Value.([]interface{}); ok {
+			index := step.Index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return []jsonMatch{{fmt.Sprintf("%s[%d]", match.Path, index), array[index]}}
+			}
+		}
+	case jsonPathSlice:
+		if array, ok := match.Value.([]interface{}); ok {
+			start := sliceBound(step.Start, len(array), 0)
+			end := sliceBound(step.End, len(array), len(array))
+			if end < start {
+				end = start
+			}
+			return []jsonMatch{{fmt.Sprintf("%s[%d:%d]", match.Path, start, end), array[start:end]}}
+		}
+	case jsonPathIterate:
+		switch typed := match.Value.(type) {
+		case map[string]interface{}:
+			matches := []jsonMatch{}
+			for _, key := range sortedKeys(typed) {
+				matches = append(matches, jsonMatch{jsonKeyPath(match.Path, key), typed[key]})
+			}
+			return matches
+		case []interface{}:
+			matches := []jsonMatch{}
+			for i, item := range typed {
+				matches = append(matches, jsonMatch{fmt.Sprintf("%s[%d]", match.Path, i), item})
+			}
+			return matches
+		}
+	case jsonPathRecurse:
+		return jsonDescendants(match)
+	}
+	return []jsonMatch{}
+}
+
+func evaluateJSONPath(path string, document interface{}) ([]jsonMatch, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	matches := []jsonMatch{{"", document}}
+	for _, step := range steps {
+		next := []jsonMatch{}
+		for _, match := range matches {
+			next = append(next, applyJSONPathStep(step, match)...)
+		}
+		matches = next
+	}
+	for i := range matches {
+		if matches[i].Path == "" {
+			matches[i].Path = "."
+		}
+	}
+	return matches, nil
+}
+
+// Strings are shown without quotes, like jq -r, so they can be pasted
+func jsonMatchItem(match jsonMatch, indent string) (AlfredItem, error) {
+	if str, ok := match.Value.(string); ok {
+		return alfredItemFromString(str, false).
+			withSubtitle(match.Path).
+			withText(str, str), nil
+	}
+
+	compact, err := marshalJSON(match.Value, "")
+	if err != nil {
+		return AlfredItem{}, err
+	}
+	pretty, err := marshalJSON(match.Value, indent)
+	if err != nil {
+		return AlfredItem{}, err
+	}
+	return alfredItemFromString(compact, false).
+		withSubtitle(fmt.Sprintf("%s (%s)", match.Path, describeJSONValue(match.Value))).
+		withText(pretty, pretty), nil
+}
+
+type jsonSubcommand struct {
+	Description string
+	Usage       string
+}
+
+var json_subcommands = map[string]jsonSubcommand{
+	"validate": {
+		Description: "Check that the JSON is valid",
+		Usage:       "validate <json>",
+	},
+	"pretty": {
+		Description: "Pretty print the JSON",
+		Usage:       "pretty [indent|tab] <json>",
+	},
+	"minify": {
+		Description: "Remove all whitespace from the JSON",
+		Usage:       "minify <json>",
+	},
+	"sort": {
+		Description: "Pretty print the JSON with the keys sorted",
+		Usage:       "sort <json>",
+	},
+	"path": {
+		Description: "Get the values at a jq style path like .items[0].name",
+		Usage:       "path <path> <json>",
+	},
+}
+
+func jsonCommands(searchQuery []string) []AlfredItem {
+	names := make([]string, 0, len(json_subcommands))
+	for name := range json_subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]AlfredItem, len(names))
+	for i, name := range names {
+		items[i] = AlfredItem{
+			UID:          name,
+			Title:        json_subcommands[name].Usage,
+			Subtitle:     json_subcommands[name].Description,
+			Arg:          []string{name + " "},
+			Autocomplete: name + " ",
+			Match:        name,
+		}
+	}
+	return filterAlfredItems(items, searchQuery)
+}
+
+// The document is the rest of the query, or stdin if the query doesn't have
+// one. JSON can't be in single quotes, so they're taken to be shell quoting
+func jsonDocument(req CommandRequest, argsBefore int) ([]byte, error) {
+	document := trimQuotePair(req.RawFrom(argsBefore), "'")
+	if strings.TrimSpace(document) != "" || req.Stdin == nil {
+		return []byte(document), nil
+	}
+	return io.ReadAll(req.Stdin)
+}
+
+// Parse the optional indent argument of pretty, returns false when the arg
+// isn't an indent so it's part of the document instead
+func parseIndentArg(arg string) (int, bool) {
+	if arg == "tab" || arg == "tabs" {
+		return 0, true
+	}
+	spaces, err := strconv.Atoi(arg)
+	if err != nil || spaces < 0 || spaces > maxJSONIndent {
+		return 0, false
+	}
+	return spaces, true
+}
+
+func jsonSummaryItems(data []byte, spaces int) ([]AlfredItem, error) {
+	document, err := parseJSONDocument(data)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	indent := jsonIndent(spaces)
+	pretty, err := prettyJSON(data, indent)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	minified, err := minifyJSON(data)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	sorted, err := sortedJSON(data, indent)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+
+	valid := alfredItemFromString("Valid JSON", false).
+		withSubtitle(describeJSONValue(document)).
+		withValid(false)
+	valid.Arg = []string{}
+	return []AlfredItem{
+		valid,
+		alfredItemFromString(pretty, false).
+			withSubtitle(fmt.Sprintf("Pretty printed with %s", describeIndent(spaces))).
+			withText(pretty, pretty),
+		alfredItemFromString(minified, false).
+			withSubtitle("Minified").
+			withText(minified, minified),
+		alfredItemFromString(sorted, false).
+			withSubtitle("Sorted keys").
+			withText(sorted, sorted),
+	}, nil
+}
+
+func jsonCommand(req CommandRequest) ([]AlfredItem, error) {
+	args := req.Args
+	spaces := req.Config.JSON.Indent
+	if len(args) == 0 && req.Stdin == nil {
+		return jsonCommands([]string{}), nil
+	}
+
+	subcmd := ""
+	if len(args) > 0 {
+		subcmd = args[0]
+	}
+	_, isSubcommand := json_subcommands[subcmd]
+	if !isSubcommand {
+		data, err := jsonDocument(req, 0)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		// Something that can't be JSON is the start of a subcommand
+		trimmed := bytes.TrimSpace(data)
+		if len(args) == 1 && jsonPathIdentRegex.Find(trimmed) != nil && !json.Valid(trimmed) {
+			return jsonCommands(args), nil
+		}
+		return jsonSummaryItems(data, spaces)
+	}
+
+	switch subcmd {
+	case "validate":
+		data, err := jsonDocument(req, 1)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		document, err := parseJSONDocument(data)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return []AlfredItem{
+			alfredItemFromString("Valid JSON", false).
+				withSubtitle(describeJSONValue(document)),
+		}, nil
+	case "pretty", "sort":
+		argsBefore := 1
+		if len(args) > 1 {
+			if indentArg, ok := parseIndentArg(args[1]); ok {
+				spaces = indentArg
+				argsBefore = 2
+			}
+		}
+		data, err := jsonDocument(req, argsBefore)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		format := prettyJSON
+		if subcmd == "sort" {
+			format = sortedJSON
+		}
+		result, err := format(data, jsonIndent(spaces))
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return []AlfredItem{alfredItemFromString(result, false).withText(result, result)}, nil
+	case "minify":
+		data, err := jsonDocument(req, 1)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		result, err := minifyJSON(data)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return []AlfredItem{alfredItemFromString(result, false)}, nil
+	default:
+		if len(args) < 2 {
+			return jsonCommands(args), nil
+		}
+		data, err := jsonDocument(req, 2)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		document, err := parseJSONDocument(data)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		// The path can have quoted keys, so only quotes around the whole path
+		// are removed
+		path := trimQuotePair(req.RawArg(1), "'\"")
+		matches, err := evaluateJSONPath(path, document)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		if len(matches) == 0 {
+			return errorAlfredItems(fmt.Sprintf("Nothing matches %s", path)), nil
+		}
+		items := make([]AlfredItem, len(matches))
+		for i, match := range matches {
+			items[i], err = jsonMatchItem(match, jsonIndent(spaces))
+			if err != nil {
+				return []AlfredItem{}, err
+			}
+		}
+		return items, nil
+	}
+}
+
+// Conversions for string pipelines, like unbase64 | jsonpretty
+var json_conversions = map[string]StringConversion{
+	"jsonpretty": {
+		Description: "Pretty print JSON",
+		ConvertWithConfig: func(config *Config, input_string string) (string, error) {
+			return prettyJSON([]byte(input_string), jsonIndent(config.JSON.Indent))
+		},
+	},
+	"jsonminify": {
+		Description: "Minify JSON",
+		Convert: func(input_string string) (string, error) {
+			return minifyJSON([]byte(input_string))
+		},
+	},
+	"jsonsort": {
+		Description: "Pretty print JSON with the keys sorted",
+		ConvertWithConfig: func(config *Config, input_string string) (string, error) {
+			return sortedJSON([]byte(input_string), jsonIndent(config.JSON.Indent))
+		},
+	},
+}
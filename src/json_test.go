@@ -0,0 +1,125 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func assertJSONTitles(t *testing.T, query string, expected ...string) {
+	t.Helper()
+	items, err := runCommandQuery(t, jsonCommand, query, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	titles := make([]string, len(items))
+	for i, item := range items {
+		titles[i] = item.Title
+	}
+	if strings.Join(titles, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Got %q expected %q", titles, expected)
+	}
+}
+
+func TestJSONValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		items, err := runCommandQuery(t, jsonCommand, `validate {"a": [1, 2]}`, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Subtitle != "object with 1 key" {
+			t.Fatalf("Got %s expected object with 1 keys", items[0].Subtitle)
+		}
+	})
+	t.Run("SyntaxError", func(t *testing.T) {
+		_, err := runCommandQuery(t, jsonCommand, "validate {\"a\": 1,\n \"b\" 2}", "")
+		if err == nil || !strings.Contains(err.Error(), "line 2 column 6") {
+			t.Fatalf("Got %v expected an error at line 2 column 6", err)
+		}
+	})
+	t.Run("TrailingData", func(t *testing.T) {
+		_, err := runCommandQuery(t, jsonCommand, `validate {} x`, "")
+		if err == nil || !strings.Contains(err.Error(), "column 4: unexpected data") {
+			t.Fatalf("Got %v expected trailing data error", err)
+		}
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		_, err := runCommandQuery(t, jsonCommand, `validate {"a": [1`, "")
+		if err == nil || !strings.Contains(err.Error(), "unexpected end of input") {
+			t.Fatalf("Got %v expected end of input error", err)
+		}
+	})
+}
+
+func TestJSONFormatting(t *testing.T) {
+	t.Run("Pretty", func(t *testing.T) {
+		assertJSONTitles(t, `pretty {"b":1,"a":[true]}`, "{\n  \"b\": 1,\n  \"a\": [\n    true\n  ]\n}")
+	})
+	t.Run("PrettyIndent", func(t *testing.T) {
+		assertJSONTitles(t, `pretty 4 {"b":1}`, "{\n    \"b\": 1\n}")
+		assertJSONTitles(t, `pretty tab {"b":1}`, "{\n\t\"b\": 1\n}")
+	})
+	t.Run("Minify", func(t *testing.T) {
+		assertJSONTitles(t, "minify { \"b\" : 1,\n \"a\": \"<x>\" }", `{"b":1,"a":"<x>"}`)
+	})
+	t.Run("Sort", func(t *testing.T) {
+		assertJSONTitles(t, `sort {"b":1,"a":1.50}`, "{\n  \"a\": 1.50,\n  \"b\": 1\n}")
+	})
+}
+
+func TestJSONPath(t *testing.T) {
+	document := `{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b", "child": {"id": 3}}], "odd key": true}`
+	t.Run("Key", func(t *testing.T) {
+		assertJSONTitles(t, "path .items[0].name "+document, "a")
+	})
+	t.Run("NegativeIndex", func(t *testing.T) {
+		assertJSONTitles(t, "path .items[-1].name "+document, "b")
+	})
+	t.Run("Recursive", func(t *testing.T) {
+		assertJSONTitles(t, "path ..id "+document, "1", "2", "3")
+	})
+	t.Run("Slice", func(t *testing.T) {
+		assertJSONTitles(t, "path .items[1:][].name "+document, "b")
+		assertJSONTitles(t, "path .items[:1] "+document, `[{"id":1,"name":"a"}]`)
+	})
+	t.Run("Iterate", func(t *testing.T) {
+		assertJSONTitles(t, "path .items[].name "+document, "a", "b")
+	})
+	t.Run("QuotedKey", func(t *testing.T) {
+		assertJSONTitles(t, `path ."odd key" `+document, "true")
+		assertJSONTitles(t, `path .["odd key"] `+document, "true")
+		assertJSONTitles(t, `path .["a]b"] {"a]b": 1}`, "1")
+		assertJSONTitles(t, `path .["a\"]"] {"a\"]": 2}`, "2")
+	})
+	t.Run("ShellQuoted", func(t *testing.T) {
+		assertJSONTitles(t, "path '.items[0].name' "+document, "a")
+		assertJSONTitles(t, `path ".items[1].name" `+document, "b")
+		assertJSONTitles(t, `path '."odd key"' `+document, "true")
+		assertJSONTitles(t, "path .items[0].id '"+document+"'", "1")
+	})
+	t.Run("MatchPath", func(t *testing.T) {
+		items, _ := runCommandQuery(t, jsonCommand, "path ..name "+document, "")
+		if items[1].Subtitle != ".items[1].name" {
+			t.Fatalf("Got %s expected .items[1].name", items[1].Subtitle)
+		}
+	})
+	t.Run("InvalidPath", func(t *testing.T) {
+		_, err := runCommandQuery(t, jsonCommand, "path .items[x] "+document, "")
+		if err == nil || !strings.Contains(err.Error(), "Invalid path at character 7") {
+			t.Fatalf("Got %v expected an invalid path error", err)
+		}
+	})
+}
+
+func TestJSONStdin(t *testing.T) {
+	items, err := runCommandQuery(t, jsonCommand, "minify", "{ \"a\": 1 }\n")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != `{"a":1}` {
+		t.Fatalf("Got %s expected {\"a\":1}", items[0].Title)
+	}
+}
+
+func TestJSONConversion(t *testing.T) {
+	assertStringCommandResult(t, []string{"unbase64", "| jsonminify eyAiYSI6IDEgfQ=="}, `{"a":1}`)
+}
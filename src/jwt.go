@@ -0,0 +1,451 @@
+package ralphred
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type jwtToken struct {
+	// The token without whitespace or a Bearer prefix
+	Raw string
+	// The header and payload as they are in the token, for verifying
+	SigningInput string
+	Header       map[string]interface{}
+	HeaderJSON   []byte
+	Payload      map[string]interface{}
+	PayloadJSON  []byte
+	Signature    []byte
+}
+
+// Registered claims, these are listed before the other claims
+var jwt_claim_descriptions = []struct {
+	Name        string
+	Description string
+}{
+	{"iss", "Issuer"},
+	{"sub", "Subject"},
+	{"aud", "Audience"},
+	{"exp", "Expiration time"},
+	{"nbf", "Not before"},
+	{"iat", "Issued at"},
+	{"jti", "JWT ID"},
+}
+
+var jwt_time_claims = map[string]bool{"exp": true, "nbf": true, "iat": true}
+
+// Tokens are base64url without padding, but some libraries add it anyway
+func decodeJWTPart(name string, part string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	var corrupt base64.CorruptInputError
+	if errors.As(err, &corrupt) {
+		return nil, fmt.Errorf("Invalid JWT %s: invalid base64 at character %d", name, int64(corrupt)+1)
+	} else if err != nil {
+		return nil, fmt.Errorf("Invalid JWT %s: %s", name, err)
+	}
+	return decoded, nil
+}
+
+func decodeJWTObject(name string, part string) (map[string]interface{}, []byte, error) {
+	data, err := decodeJWTPart(name, part)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	err = decoder.Decode(&object)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid JWT %s: %s isn't a JSON object", name, name)
+	}
+	return object, data, nil
+}
+
+func parseJWT(token string) (jwtToken, error) {
+	token = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(token), "Bearer "))
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtToken{}, fmt.Errorf("Invalid JWT: expected 3 parts separated by . got %d", len(parts))
+	}
+
+	header, headerJSON, err := decodeJWTObject("header", parts[0])
+	if err != nil {
+		return jwtToken{}, err
+	}
+	payload, payloadJSON, err := decodeJWTObject("payload", parts[1])
+	if err != nil {
+		return jwtToken{}, err
+	}
+	signature, err := decodeJWTPart("signature", parts[2])
+	if err != nil {
+		return jwtToken{}, err
+	}
+	return jwtToken{
+		Raw:          token,
+		SigningInput: parts[0] + "." + parts[1],
+		Header:       header,
+		HeaderJSON:   headerJSON,
+		Payload:      payload,
+		PayloadJSON:  payloadJSON,
+		Signature:    signature,
+	}, nil
+}
+
+func (token jwtToken) Algorithm() string {
+	alg, _ := token.Header["alg"].(string)
+	return alg
+}
+
+// Time claims are seconds since the epoch, fractions are allowed
+func (token jwtToken) TimeClaim(name string) (time.Time, bool) {
+	number, ok := token.Payload[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole := int64(seconds)
+	return time.Unix(whole, int64((seconds-float64(whole))*1e9)), true
+}
+
+// Describe whether the token is expired or not valid yet
+func (token jwtToken) Status(config *Config) string {
+	current := now()
+	layout := displayTimeLayout(config)
+	if notBefore, exists := token.TimeClaim("nbf"); exists && current.Before(notBefore) {
+		return fmt.Sprintf("Not valid for another %s", roughDuration(notBefore.Sub(current)))
+	}
+	expires, exists := token.TimeClaim("exp")
+	if !exists {
+		return "Doesn't expire"
+	}
+	if !current.Before(expires) {
+		return fmt.Sprintf("Expired %s ago at %s", roughDuration(current.Sub(expires)), expires.Format(layout))
+	}
+	return fmt.Sprintf("Valid for another %s until %s", roughDuration(expires.Sub(current)), expires.Format(layout))
+}
+
+func jwtClaimNames(payload map[string]interface{}) []string {
+	names := []string{}
+	registered := map[string]bool{}
+	for _, claim := range jwt_claim_descriptions {
+		registered[claim.Name] = true
+		if _, exists := payload[claim.Name]; exists {
+			names = append(names, claim.Name)
+		}
+	}
+	others := []string{}
+	for name := range payload {
+		if !registered[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+func jwtClaimDescription(name string) string {
+	for _, claim := range jwt_claim_descriptions {
+		if claim.Name == name {
+			return claim.Description
+		}
+	}
+	return ""
+}
+
+func jwtClaimItem(config *Config, token jwtToken, name string) (AlfredItem, error) {
+	value := token.Payload[name]
+	subtitle := jwtClaimDescription(name)
+
+	var display string
+	if claimTime, isTime := token.TimeClaim(name); isTime && jwt_time_claims[name] {
+		display = claimTime.Format(displayTimeLayout(config))
+		relative := "from now"
+		if claimTime.Before(now()) {
+			relative = "ago"
+		}
+		subtitle = fmt.Sprintf("%s, %s %s (%s)", subtitle, roughDuration(now().Sub(claimTime)), relative, value)
+	} else if str, ok := value.(string); ok {
+		display = str
+	} else {
+		var err error
+		display, err = marshalJSON(value, "")
+		if err != nil {
+			return AlfredItem{}, err
+		}
+	}
+
+	if subtitle == "" {
+		subtitle = "Claim"
+	}
+	item := alfredItemFromString(display, false).withSubtitle(subtitle)
+	item.Title = fmt.Sprintf("%s: %s", name, display)
+	item.UID = name
+	if _, isTime := token.TimeClaim(name); isTime && jwt_time_claims[name] {
+		// Autocompleting shows the time in every output format
+		item.Autocomplete = token.Raw + " " + name
+	}
+	return item, nil
+}
+
+// The time claim in each of the output formats
+func jwtTimeClaimItems(config *Config, token jwtToken, name string) ([]AlfredItem, error) {
+	claimTime, exists := token.TimeClaim(name)
+	if !exists {
+		return []AlfredItem{}, fmt.Errorf("Token doesn't have an %s time", name)
+	}
+	items := timeFormatItems(config, claimTime)
+	for i, item := range items {
+		items[i] = item.withSubtitle(fmt.Sprintf("%s (%s)", jwtClaimDescription(name), item.UID))
+	}
+	return items, nil
+}
+
+func jwtHashes(alg string) (crypto.Hash, func() hash.Hash, error) {
+	switch alg[len(alg)-3:] {
+	case "256":
+		return crypto.SHA256, sha256.New, nil
+	case "384":
+		return crypto.SHA384, sha512.New384, nil
+	case "512":
+		return crypto.SHA512, sha512.New, nil
+	}
+	return 0, nil, fmt.Errorf("Unsupported algorithm %s", alg)
+}
+
+var pemRegex = regexp.MustCompile(`(?s)(-----BEGIN [A-Z ]+-----)(.*?)(-----END [A-Z ]+-----)`)
+
+// PEM pasted into alfred loses its line breaks, so put them back
+func restorePEMLines(key string) string {
+	return pemRegex.ReplaceAllStringFunc(key, func(block string) string {
+		parts := pemRegex.FindStringSubmatch(block)
+		return strings.Join([]string{parts[1], strings.Join(strings.Fields(parts[2]), "\n"), parts[3]}, "\n")
+	})
+}
+
+// Prefixes that mark a key as the path of a PEM file
+var jwt_key_path_prefixes = []string{"@", "file:"}
+
+// The path of the key file if the key is marked as a path
+func jwtKeyPath(key string) (string, bool) {
+	key = strings.TrimSpace(key)
+	for _, prefix := range jwt_key_path_prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix), true
+		}
+	}
+	return key, false
+}
+
+// The key can be PEM pasted into the query or the path of a PEM file. Any
+// key is a valid secret, so only algorithms that need a public key read files
+func parseJWTPublicKey(key string) (crypto.PublicKey, error) {
+	data := []byte(restorePEMLines(key))
+	if !strings.Contains(key, "-----BEGIN") {
+		path, _ := jwtKeyPath(key)
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Expected a PEM public key or the path of one: %s", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("Unable to find a PEM block in the public key")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+// Whether the key is PEM or marked as the path of a key file rather than a
+// secret. Secrets aren't looked up on disk, they could be anything
+func isJWTPublicKey(key string) bool {
+	_, isPath := jwtKeyPath(key)
+	return isPath || strings.Contains(key, "-----BEGIN")
+}
+
+var jwt_ecdsa_curves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// Verify the signature with a secret for HS algorithms and a public key
+// for RS, PS and ES algorithms. The kind of key has to match the algorithm,
+// otherwise a token could be HMACed with a public key and pass
+func verifyJWT(token jwtToken, key string) error {
+	alg := token.Algorithm()
+	if alg == "" || alg == "none" {
+		return errors.New("Token isn't signed")
+	}
+	if len(alg) != 5 {
+		return fmt.Errorf("Unsupported algorithm %s", alg)
+	}
+	hashType, newHash, err := jwtHashes(alg)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(alg, "HS") && isJWTPublicKey(key) {
+		return fmt.Errorf("%s needs a secret, not a public key", alg)
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		mac := hmac.New(newHash, []byte(key))
+		mac.Write([]byte(token.SigningInput))
+		if !hmac.Equal(mac.Sum(nil), token.Signature) {
+			return errors.New("Signature doesn't match the secret")
+		}
+		return nil
+	}
+
+	publicKey, err := parseJWTPublicKey(key)
+	if err != nil {
+		return err
+	}
+	hasher := newHash()
+	hasher.Write([]byte(token.SigningInput))
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an RSA public key", alg)
+		}
+		if alg[:2] == "RS" {
+			err = rsa.VerifyPKCS1v15(rsaKey, hashType, digest, token.Signature)
+		} else {
+			err = rsa.VerifyPSS(rsaKey, hashType, digest, token.Signature, nil)
+		}
+		if err != nil {
+			return errors.New("Signature doesn't match the public key")
+		}
+		return nil
+	case "ES":
+		ecKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an ECDSA public key", alg)
+		}
+		curve, exists := jwt_ecdsa_curves[alg]
+		if !exists {
+			return fmt.Errorf("Unsupported algorithm %s", alg)
+		}
+		if ecKey.Curve != curve {
+			return fmt.Errorf("%s needs a %s key got %s", alg, curve.Params().Name, ecKey.Curve.Params().Name)
+		}
+		// The signature is r and s concatenated, each the size of the curve
+		size := (curve.Params().BitSize + 7) / 8
+		if len(token.Signature) != 2*size {
+			return fmt.Errorf("Invalid %s signature length, expected %d bytes got %d", alg, 2*size, len(token.Signature))
+		}
+		r := new(big.Int).SetBytes(token.Signature[:size])
+		s := new(big.Int).SetBytes(token.Signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("Signature doesn't match the public key")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unsupported algorithm %s", alg)
+}
+
+func jwtItems(config *Config, token jwtToken) ([]AlfredItem, error) {
+	statusItem := alfredItemFromString(token.Status(config), false).
+		withSubtitle(fmt.Sprintf("Signed with %s, not verified", token.Algorithm())).
+		withValid(false)
+	statusItem.UID = "status"
+	statusItem.Arg = []string{}
+
+	header := string(token.HeaderJSON)
+	payload := string(token.PayloadJSON)
+	items := []AlfredItem{
+		statusItem,
+		alfredItemFromString(header, false).withSubtitle("Header").withText(header, header),
+		alfredItemFromString(payload, false).withSubtitle("Payload").withText(payload, payload),
+	}
+	for _, name := range jwtClaimNames(token.Payload) {
+		item, err := jwtClaimItem(config, token, name)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Decode a token, or with verify check its signature too
+func jwtCommand(req CommandRequest) ([]AlfredItem, error) {
+	args := req.Args
+	if len(args) == 0 {
+		return []AlfredItem{
+			alfredItemFromString("Paste a JWT", false).
+				withSubtitle("jwt <token> [exp|nbf|iat], jwt verify <token> <secret|public key|@key path>").
+				withValid(false),
+		}, nil
+	}
+
+	if args[0] != "verify" && len(args) == 2 && jwt_time_claims[args[1]] {
+		token, err := parseJWT(args[0])
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return jwtTimeClaimItems(req.Config, token, args[1])
+	}
+
+	if args[0] != "verify" {
+		token, err := parseJWT(req.RawAfter(0))
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return jwtItems(req.Config, token)
+	}
+
+	if len(args) < 3 {
+		return []AlfredItem{}, errors.New("Verifying needs a token and a secret or public key")
+	}
+	token, err := parseJWT(args[1])
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	err = verifyJWT(token, req.RawAfter(2))
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	items, err := jwtItems(req.Config, token)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	items[0].Subtitle = fmt.Sprintf("Signature verified with %s", token.Algorithm())
+	return items, nil
+}
@@ -0,0 +1,270 @@
+package ralphred
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useFrozenNow(t *testing.T, frozen time.Time) {
+	t.Helper()
+	origNow := now
+	t.Cleanup(func() { now = origNow })
+	now = func() time.Time { return frozen }
+}
+
+func makeJWT(header string, payload string, sign func(input string) []byte) string {
+	input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input))
+}
+
+func hs256Signer(secret string) func(string) []byte {
+	return func(input string) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(input))
+		return mac.Sum(nil)
+	}
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+const testJWTPayload = `{"sub":"1234","name":"Ada","exp":1663782330,"iat":1663775130,"admin":true}`
+
+func TestJWTDecode(t *testing.T) {
+	useFrozenNow(t, time.Unix(1663778730, 0))
+	token := makeJWT(`{"alg":"HS256","typ":"JWT"}`, testJWTPayload, hs256Signer("secret"))
+
+	items, err := runCommandQuery(t, jwtCommand, token, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if !strings.HasPrefix(items[0].Title, "Valid for another 1 hour") {
+		t.Fatalf("Got %s expected the token to be valid", items[0].Title)
+	}
+	titles := []string{}
+	for _, item := range items[3:] {
+		titles = append(titles, strings.SplitN(item.Title, ":", 2)[0])
+	}
+	if strings.Join(titles, ",") != "sub,exp,iat,admin,name" {
+		t.Fatalf("Got %s expected registered claims first", strings.Join(titles, ","))
+	}
+	exp := items[4]
+	expected := "exp: " + time.Unix(1663782330, 0).Format(time.RFC3339)
+	if exp.Title != expected {
+		t.Fatalf("Got %s expected %s", exp.Title, expected)
+	}
+	if exp.Subtitle != "Expiration time, 1 hour from now (1663782330)" {
+		t.Fatalf("Got %s", exp.Subtitle)
+	}
+	if exp.Autocomplete != token+" exp" {
+		t.Fatalf("Got %s expected to autocomplete the exp formats", exp.Autocomplete)
+	}
+}
+
+func TestJWTTimeClaim(t *testing.T) {
+	token := makeJWT(`{"alg":"HS256","typ":"JWT"}`, testJWTPayload, hs256Signer("secret"))
+	t.Run("OutputFormats", func(t *testing.T) {
+		req := newCommandRequest(token + " iat")
+		req.Config.DateTimeMath.OutputFormats = map[string]string{"Year": "2006"}
+		items, err := jwtCommand(req)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		titles := map[string]string{}
+		for _, item := range items {
+			titles[item.UID] = item.Title
+		}
+		if titles["Year"] != "2022" || titles["UnixTimeStamp"] != "1663775130" {
+			t.Fatalf("Got %v expected the iat in every format", titles)
+		}
+		if items[0].Subtitle != "Issued at (Date)" {
+			t.Fatalf("Got %s expected the claim and format", items[0].Subtitle)
+		}
+	})
+	t.Run("MissingClaim", func(t *testing.T) {
+		_, err := runCommandQuery(t, jwtCommand, token+" nbf", "")
+		if err == nil || err.Error() != "Token doesn't have an nbf time" {
+			t.Fatalf("Got %v expected a missing claim error", err)
+		}
+	})
+}
+
+func TestJWTStatus(t *testing.T) {
+	token := makeJWT(`{"alg":"HS256"}`, `{"exp":1000,"nbf":500}`, hs256Signer("secret"))
+	parsed, err := parseJWT(token)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	t.Run("Expired", func(t *testing.T) {
+		useFrozenNow(t, time.Unix(1000+3*24*60*60, 0))
+		if status := parsed.Status(defaultConfig()); !strings.HasPrefix(status, "Expired 3 days ago") {
+			t.Fatalf("Got %s expected expired", status)
+		}
+	})
+	t.Run("NotValidYet", func(t *testing.T) {
+		useFrozenNow(t, time.Unix(400, 0))
+		if status := parsed.Status(defaultConfig()); status != "Not valid for another 1 minute" {
+			t.Fatalf("Got %s expected not valid yet", status)
+		}
+	})
+}
+
+func TestJWTInvalid(t *testing.T) {
+	_, err := runCommandQuery(t, jwtCommand, "abc.def", "")
+	if err == nil || !strings.Contains(err.Error(), "expected 3 parts") {
+		t.Fatalf("Got %v expected a parts error", err)
+	}
+	_, err = runCommandQuery(t, jwtCommand, "eyJhbGciOiJIUzI1NiJ9.e!J9.sig", "")
+	if err == nil || !strings.Contains(err.Error(), "Invalid JWT payload: invalid base64 at character 2") {
+		t.Fatalf("Got %v expected a base64 error", err)
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	t.Run("HMAC", func(t *testing.T) {
+		token := makeJWT(`{"alg":"HS256"}`, `{"sub":"a"}`, hs256Signer("my secret"))
+		items, err := runCommandQuery(t, jwtCommand, "verify "+token+" my secret", "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Subtitle != "Signature verified with HS256" {
+			t.Fatalf("Got %s expected verified", items[0].Subtitle)
+		}
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" wrong", "")
+		if err == nil || err.Error() != "Signature doesn't match the secret" {
+			t.Fatalf("Got %v expected a mismatch", err)
+		}
+	})
+	t.Run("RSA", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		token := makeJWT(`{"alg":"RS256"}`, `{"sub":"a"}`, func(input string) []byte {
+			digest := sha256.Sum256([]byte(input))
+			signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			return signature
+		})
+		// Alfred turns the line breaks into spaces
+		publicKey := strings.ReplaceAll(publicKeyPEM(t, &key.PublicKey), "\n", " ")
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" "+publicKey, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+	})
+	t.Run("ECDSA", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		token := makeJWT(`{"alg":"ES256"}`, `{"sub":"a"}`, func(input string) []byte {
+			digest := sha256.Sum256([]byte(input))
+			r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature
+		})
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" '"+publicKeyPEM(t, &key.PublicKey)+"'", "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+
+		other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" '"+publicKeyPEM(t, &other.PublicKey)+"'", "")
+		if err == nil || err.Error() != "Signature doesn't match the public key" {
+			t.Fatalf("Got %v expected a mismatch", err)
+		}
+
+		p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" '"+publicKeyPEM(t, &p384.PublicKey)+"'", "")
+		if err == nil || err.Error() != "ES256 needs a P-256 key got P-384" {
+			t.Fatalf("Got %v expected a curve mismatch", err)
+		}
+
+		short := makeJWT(`{"alg":"ES256"}`, `{"sub":"a"}`, func(string) []byte { return make([]byte, 62) })
+		_, err = runCommandQuery(t, jwtCommand, "verify "+short+" '"+publicKeyPEM(t, &key.PublicKey)+"'", "")
+		if err == nil || err.Error() != "Invalid ES256 signature length, expected 64 bytes got 62" {
+			t.Fatalf("Got %v expected a length error", err)
+		}
+	})
+	t.Run("KeyMustMatchAlgorithm", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		publicKey := publicKeyPEM(t, &key.PublicKey)
+		keyPath := filepath.Join(t.TempDir(), "pub.pem")
+		if err := os.WriteFile(keyPath, []byte(publicKey), 0600); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+
+		// HMACed with the public key, which would pass if it was used as
+		// the secret
+		for _, key := range []string{publicKey, "@" + keyPath, "file:" + keyPath} {
+			token := makeJWT(`{"alg":"HS256"}`, `{"sub":"a"}`, hs256Signer(key))
+			_, err = runCommandQuery(t, jwtCommand, "verify "+token+" '"+key+"'", "")
+			if err == nil || err.Error() != "HS256 needs a secret, not a public key" {
+				t.Fatalf("Got %v expected the public key to be refused", err)
+			}
+		}
+
+		token := makeJWT(`{"alg":"RS256"}`, `{"sub":"a"}`, hs256Signer("secret"))
+		_, err = runCommandQuery(t, jwtCommand, "verify "+token+" secret", "")
+		if err == nil || !strings.HasPrefix(err.Error(), "Expected a PEM public key or the path of one") {
+			t.Fatalf("Got %v expected the secret to be refused", err)
+		}
+	})
+	t.Run("SecretIsntReadFromDisk", func(t *testing.T) {
+		secretPath := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(secretPath, []byte("from disk"), 0600); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		token := makeJWT(`{"alg":"HS256"}`, `{"sub":"a"}`, hs256Signer(secretPath))
+		_, err := runCommandQuery(t, jwtCommand, "verify "+token+" "+secretPath, "")
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+	})
+	t.Run("KeyPath", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		keyPath := filepath.Join(t.TempDir(), "pub.pem")
+		if err := os.WriteFile(keyPath, []byte(publicKeyPEM(t, &key.PublicKey)), 0600); err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		token := makeJWT(`{"alg":"RS256"}`, `{"sub":"a"}`, func(input string) []byte {
+			digest := sha256.Sum256([]byte(input))
+			signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			return signature
+		})
+		for _, path := range []string{keyPath, "@" + keyPath, "file:" + keyPath} {
+			_, err = runCommandQuery(t, jwtCommand, "verify "+token+" "+path, "")
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,481 @@
+package ralphred
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Directories that hold the code for a language, stripped from the start of
+// paths. Merged with modules.source_roots from the config
+var default_source_roots = map[string][]string{
+	"python": {"src"},
+	"java":   {"src/main/java", "src/test/java", "src/main/kotlin", "src/test/kotlin", "src"},
+	"rust":   {"src"},
+}
+
+type moduleContext struct {
+	Roots []string
+	// Go module path for relative paths
+	Prefix string
+	// File a JS/TS import is written in, empty if it wasn't given
+	From string
+}
+
+type moduleLanguage struct {
+	Name string
+	// What the module path is called, like "Python module"
+	Description string
+	Extensions  []string
+	ToModule    func(filePath string, context moduleContext) (string, error)
+	ToPath      func(module string, context moduleContext) ([]string, error)
+}
+
+// The path relative to its source root. Absolute paths are cut after the
+// last source root in them, and are an error without one since the module
+// would have every directory in it
+func stripSourceRoot(filePath string, roots []string) (string, error) {
+	filePath = strings.TrimPrefix(filepath.ToSlash(filePath), "./")
+	for _, root := range roots {
+		root = strings.Trim(filepath.ToSlash(root), "/") + "/"
+		if strings.HasPrefix(filePath, root) {
+			return filePath[len(root):], nil
+		}
+		if index := strings.LastIndex(filePath, "/"+root); index != -1 && path.IsAbs(filePath) {
+			return filePath[index+len(root)+1:], nil
+		}
+	}
+	if path.IsAbs(filePath) {
+		return "", fmt.Errorf("%s isn't in a source root (%s), add its root to modules.source_roots", filePath, strings.Join(roots, ", "))
+	}
+	return filePath, nil
+}
+
+func trimExtension(filePath string, extensions []string) string {
+	for _, extension := range extensions {
+		if strings.HasSuffix(filePath, extension) {
+			return strings.TrimSuffix(filePath, extension)
+		}
+	}
+	return filePath
+}
+
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// Packages on disk are found by walking up the directories with an
+// __init__.py, so the source root doesn't matter
+func pythonPackageParts(filePath string) []string {
+	parts := []string{}
+	dir := filepath.Dir(filePath)
+	for fileExists(filepath.Join(dir, "__init__.py")) {
+		parts = append([]string{filepath.Base(dir)}, parts...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return parts
+}
+
+func pythonToModule(filePath string, context moduleContext) (string, error) {
+	name := trimExtension(filepath.Base(filePath), []string{".py", ".pyi"})
+	var parts []string
+	if filepath.IsAbs(filePath) {
+		parts = pythonPackageParts(filePath)
+	}
+	if len(parts) > 0 {
+		parts = append(parts, name)
+	} else {
+		relative, err := stripSourceRoot(filePath, context.Roots)
+		if err != nil {
+			return "", err
+		}
+		parts = strings.Split(trimExtension(relative, []string{".py", ".pyi"}), "/")
+	}
+	if parts[len(parts)-1] == "__init__" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 {
+		return "", errors.New("An __init__.py at the source root isn't a module")
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func pythonToPath(module string, context moduleContext) ([]string, error) {
+	filePath := strings.ReplaceAll(module, ".", "/")
+	return []string{filePath + ".py", filePath + "/__init__.py"}, nil
+}
+
+// The module path from the module directive of a go.mod file
+func parseGoModModule(data string) (string, error) {
+	for _, line := range strings.Split(data, "\n") {
+		if comment := strings.Index(line, "//"); comment != -1 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted, nil
+		}
+		return fields[1], nil
+	}
+	return "", errors.New("go.mod doesn't have a module line")
+}
+
+// Find the go.mod above an absolute path, returning its directory and module
+func findGoModule(dir string) (string, string, error) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			module, err := parseGoModModule(string(data))
+			return dir, module, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", errors.New("Unable to find a go.mod above the path")
+		}
+		dir = parent
+	}
+}
+
+// Go imports are packages, so a file is imported by its directory
+func goToModule(filePath string, context moduleContext) (string, error) {
+	dir := filePath
+	if strings.HasSuffix(filePath, ".go") {
+		dir = filepath.Dir(filePath)
+	}
+	if filepath.IsAbs(dir) {
+		moduleDir, module, err := findGoModule(dir)
+		if err != nil {
+			return "", err
+		}
+		relative, _ := filepath.Rel(moduleDir, dir)
+		return path.Join(module, filepath.ToSlash(relative)), nil
+	}
+	if context.Prefix == "" {
+		return "", errors.New("Set modules.module_prefix to convert relative Go paths")
+	}
+	return path.Join(context.Prefix, filepath.ToSlash(dir)), nil
+}
+
+func goToPath(module string, context moduleContext) ([]string, error) {
+	if context.Prefix == "" {
+		return nil, errors.New("Set modules.module_prefix to convert Go import paths")
+	}
+	if module != context.Prefix && !strings.HasPrefix(module, context.Prefix+"/") {
+		return nil, fmt.Errorf("%s isn't in the module %s", module, context.Prefix)
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(module, context.Prefix), "/")
+	if dir == "" {
+		dir = "."
+	}
+	return []string{dir}, nil
+}
+
+var java_extensions = []string{".java", ".kt", ".kts"}
+
+func javaToModule(filePath string, context moduleContext) (string, error) {
+	relative, err := stripSourceRoot(filePath, context.Roots)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(trimExtension(relative, java_extensions), "/", "."), nil
+}
+
+func javaToPath(module string, context moduleContext) ([]string, error) {
+	filePath := strings.ReplaceAll(module, ".", "/")
+	return []string{filePath + ".java", filePath + ".kt"}, nil
+}
+
+func rustToModule(filePath string, context moduleContext) (string, error) {
+	relative, err := stripSourceRoot(filePath, context.Roots)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(trimExtension(relative, []string{".rs"}), "/")
+	switch parts[len(parts)-1] {
+	case "mod":
+		parts = parts[:len(parts)-1]
+	case "lib", "main":
+		if len(parts) == 1 {
+			parts = []string{}
+		}
+	}
+	return strings.Join(append([]string{"crate"}, parts...), "::"), nil
+}
+
+func rustToPath(module string, context moduleContext) ([]string, error) {
+	parts := strings.Split(module, "::")
+	if parts[0] != "crate" {
+		return nil, errors.New("Only crate:: paths can be turned into a file path")
+	}
+	if len(parts) == 1 {
+		return []string{"lib.rs", "main.rs"}, nil
+	}
+	filePath := strings.Join(parts[1:], "/")
+	return []string{filePath + ".rs", filePath + "/mod.rs"}, nil
+}
+
+var js_extensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".mts", ".cts"}
+
+// Imports are relative to the importing file, or the current directory when
+// there isn't one. index files are imported by their directory
+func jsToModule(filePath string, context moduleContext) (string, error) {
+	target := path.Clean(filepath.ToSlash(trimExtension(filePath, js_extensions)))
+	if path.Base(target) == "index" {
+		target = path.Dir(target)
+	}
+	fromDir := "."
+	if context.From != "" {
+		fromDir = path.Dir(filepath.ToSlash(context.From))
+	}
+	relative, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return "", err
+	}
+	relative = filepath.ToSlash(relative)
+	if relative != "." && relative != ".." && !strings.HasPrefix(relative, "../") {
+		relative = "./" + relative
+	}
+	return relative, nil
+}
+
+// The extension of the importing file is used, since that's what's likely
+// being imported
+func jsToPath(module string, context moduleContext) ([]string, error) {
+	extension := ".ts"
+	fromDir := "."
+	if context.From != "" {
+		fromDir = path.Dir(filepath.ToSlash(context.From))
+		if fromExtension := path.Ext(context.From); fromExtension != "" {
+			extension = fromExtension
+		}
+	}
+	target := path.Join(fromDir, module)
+	return []string{target + extension, target + "/index" + extension}, nil
+}
+
+var module_languages = []moduleLanguage{
+	{
+		Name:        "python",
+		Description: "Python module",
+		Extensions:  []string{".py", ".pyi"},
+		ToModule:    pythonToModule,
+		ToPath:      pythonToPath,
+	},
+	{
+		Name:        "go",
+		Description: "Go import path",
+		Extensions:  []string{".go"},
+		ToModule:    goToModule,
+		ToPath:      goToPath,
+	},
+	{
+		Name:        "java",
+		Description: "Java/Kotlin class",
+		Extensions:  java_extensions,
+		ToModule:    javaToModule,
+		ToPath:      javaToPath,
+	},
+	{
+		Name:        "rust",
+		Description: "Rust module path",
+		Extensions:  []string{".rs"},
+		ToModule:    rustToModule,
+		ToPath:      rustToPath,
+	},
+	{
+		Name:        "js",
+		Description: "JS/TS import",
+		Extensions:  js_extensions,
+		ToModule:    jsToModule,
+		ToPath:      jsToPath,
+	},
+}
+
+var module_language_aliases = map[string]string{
+	"py":         "python",
+	"golang":     "go",
+	"kotlin":     "java",
+	"kt":         "java",
+	"rs":         "rust",
+	"javascript": "js",
+	"typescript": "js",
+	"ts":         "js",
+}
+
+func findModuleLanguage(name string) (moduleLanguage, bool) {
+	name = strings.ToLower(name)
+	if alias, exists := module_language_aliases[name]; exists {
+		name = alias
+	}
+	for _, language := range module_languages {
+		if language.Name == name {
+			return language, true
+		}
+	}
+	return moduleLanguage{}, false
+}
+
+func (config *Config) moduleContext(language string) moduleContext {
+	roots := default_source_roots[language]
+	if configured, exists := config.Modules.SourceRoots[language]; exists {
+		roots = configured
+	}
+	return moduleContext{Roots: roots, Prefix: config.Modules.ModulePrefix}
+}
+
+func isModuleFilePath(input string, language moduleLanguage) bool {
+	for _, extension := range language.Extensions {
+		if strings.HasSuffix(input, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Languages a module path could be from, guessed from how it looks
+func guessModuleLanguages(config *Config, module string) []string {
+	firstSegment := strings.SplitN(module, "/", 2)[0]
+	switch {
+	case strings.HasPrefix(module, "./") || strings.HasPrefix(module, "../"):
+		return []string{"js"}
+	case module == "crate" || strings.Contains(module, "::"):
+		return []string{"rust"}
+	case strings.Contains(module, "/") && strings.Contains(firstSegment, "."):
+		return []string{"go"}
+	case config.Modules.ModulePrefix != "" && strings.HasPrefix(module, config.Modules.ModulePrefix):
+		return []string{"go"}
+	}
+	return []string{"python", "java"}
+}
+
+type moduleConversion struct {
+	Language moduleLanguage
+	Results  []string
+	ToPath   bool
+}
+
+// Convert a file path to a module path or back. The language comes from the
+// file extension or how the module path looks when it isn't given
+func convertModulePath(config *Config, input string, languageName string, from string) ([]moduleConversion, error) {
+	input = strings.TrimSpace(input)
+	candidates := module_languages
+	if languageName != "" {
+		language, exists := findModuleLanguage(languageName)
+		if !exists {
+			return nil, fmt.Errorf("Unknown language %s", languageName)
+		}
+		candidates = []moduleLanguage{language}
+	}
+
+	for _, language := range candidates {
+		if !isModuleFilePath(input, language) {
+			continue
+		}
+		context := config.moduleContext(language.Name)
+		context.From = from
+		module, err := language.ToModule(input, context)
+		if err != nil {
+			return nil, err
+		}
+		return []moduleConversion{{Language: language, Results: []string{module}}}, nil
+	}
+
+	names := guessModuleLanguages(config, input)
+	if languageName != "" {
+		names = []string{candidates[0].Name}
+	}
+	conversions := []moduleConversion{}
+	for _, name := range names {
+		language, _ := findModuleLanguage(name)
+		context := config.moduleContext(language.Name)
+		context.From = from
+		paths, err := language.ToPath(input, context)
+		if err != nil {
+			return nil, err
+		}
+		conversions = append(conversions, moduleConversion{Language: language, Results: paths, ToPath: true})
+	}
+	return conversions, nil
+}
+
+var module_usage = []AlfredItem{
+	alfredItemFromString("module [language] <path or module> [from <file>]", false).
+		withSubtitle("Convert between file paths and Python, Go, Java/Kotlin, Rust or JS/TS module paths").
+		withValid(false),
+}
+
+func moduleCommand(config *Config, args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return module_usage, nil
+	}
+	languageName := ""
+	if _, exists := findModuleLanguage(args[0]); exists && len(args) > 1 {
+		languageName = args[0]
+		args = args[1:]
+	}
+	from := ""
+	if len(args) > 2 && args[len(args)-2] == "from" {
+		from = args[len(args)-1]
+		args = args[:len(args)-2]
+	}
+
+	conversions, err := convertModulePath(config, strings.Join(args, " "), languageName, from)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	items := []AlfredItem{}
+	for _, conversion := range conversions {
+		for _, result := range conversion.Results {
+			subtitle := conversion.Language.Description
+			if conversion.ToPath {
+				subtitle = fmt.Sprintf("File path for the %s", strings.ToLower(subtitle[:1])+subtitle[1:])
+			}
+			items = append(items, alfredItemFromString(result, false).withSubtitle(subtitle))
+		}
+	}
+	return items, nil
+}
+
+// A conversion between file paths and module paths of one language, or any
+// language when it's empty
+func modulePathConversion(languageName string) func(*Config, string) (string, error) {
+	return func(config *Config, input_string string) (string, error) {
+		conversions, err := convertModulePath(config, input_string, languageName, "")
+		if err != nil {
+			return "", err
+		}
+		return conversions[0].Results[0], nil
+	}
+}
+
+// Any string looks like a path or module, so these aren't previewed. pymod
+// and unpymod are kept for compatibility as aliases of modpath for Python
+var module_conversions = map[string]StringConversion{
+	"pymod": {
+		Description:       "Convert a file path to a Python module path, or back",
+		ConvertWithConfig: modulePathConversion("python"),
+		NoPreview:         true,
+	},
+	"unpymod": {
+		Description:       "Convert a Python module path to a file path, or back",
+		ConvertWithConfig: modulePathConversion("python"),
+		NoPreview:         true,
+	},
+	"modpath": {
+		Description:       "Convert between a file path and a module path, guessing the language",
+		ConvertWithConfig: modulePathConversion(""),
+		NoPreview:         true,
+	},
+}
@@ -0,0 +1,155 @@
+package ralphred
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		filePath := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(filePath), 0700)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		err = os.WriteFile(filePath, []byte(contents), 0600)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+	}
+	return dir
+}
+
+func assertModuleResults(t *testing.T, query string, expected string) {
+	t.Helper()
+	assertConfiguredModuleResults(t, defaultConfig(), query, expected)
+}
+
+func assertConfiguredModuleResults(t *testing.T, config *Config, query string, expected string) {
+	t.Helper()
+	items, err := moduleCommand(config, strings.Fields(query))
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	results := []string{}
+	for _, item := range items {
+		results = append(results, item.Title)
+	}
+	if strings.Join(results, ", ") != expected {
+		t.Fatalf("Got %s expected %s", strings.Join(results, ", "), expected)
+	}
+}
+
+func TestPythonModules(t *testing.T) {
+	t.Run("SourceRoot", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"pymod", "src/pkg/mod.py"}, "pkg.mod")
+	})
+	t.Run("Init", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"pymod", "./pkg/sub/__init__.py"}, "pkg.sub")
+	})
+	t.Run("ConfiguredSourceRoot", func(t *testing.T) {
+		config := defaultConfig()
+		config.Modules.SourceRoots = map[string][]string{"python": {"lib/python"}}
+		assertConfiguredModuleResults(t, config, "lib/python/pkg/mod.py", "pkg.mod")
+		assertConfiguredModuleResults(t, config, "src/pkg/mod.py", "src.pkg.mod")
+	})
+	t.Run("PackagesOnDisk", func(t *testing.T) {
+		dir := writeTestFiles(t, map[string]string{
+			"code/app/__init__.py":      "",
+			"code/app/util/__init__.py": "",
+			"code/app/util/text.py":     "",
+		})
+		assertStringCommandResult(t, []string{"pymod", filepath.Join(dir, "code/app/util/text.py")}, "app.util.text")
+	})
+	t.Run("AbsoluteWithoutRoot", func(t *testing.T) {
+		dir := writeTestFiles(t, map[string]string{"code/app/text.py": ""})
+		_, err := pythonToModule(filepath.Join(dir, "code/app/text.py"), defaultConfig().moduleContext("python"))
+		if err == nil || !strings.Contains(err.Error(), "isn't in a source root (src)") {
+			t.Fatalf("Got %v expected a missing source root error", err)
+		}
+		assertStringCommandResult(t, []string{"pymod", filepath.Join(dir, "code/src/app/text.py")}, "app.text")
+	})
+	t.Run("Aliases", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"pymod", "pkg.mod"}, "pkg/mod.py")
+		assertStringCommandResult(t, []string{"unpymod", "pkg/mod.py"}, "pkg.mod")
+	})
+	t.Run("NotPreviewed", func(t *testing.T) {
+		items, err := stringCommand(defaultConfig(), []string{"?", "/tmp/pkg/mod.py"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		for _, item := range items {
+			if _, isModule := module_conversions[item.UID]; isModule {
+				t.Fatalf("Got a preview for %s", item.UID)
+			}
+		}
+	})
+	t.Run("ToPath", func(t *testing.T) {
+		assertModuleResults(t, "python pkg.mod", "pkg/mod.py, pkg/mod/__init__.py")
+	})
+}
+
+func TestGoModules(t *testing.T) {
+	t.Run("GoMod", func(t *testing.T) {
+		dir := writeTestFiles(t, map[string]string{
+			"repo/go.mod":             "// The module\nmodule \"example.com/repo\" // comment\n\ngo 1.17\n",
+			"repo/internal/db/sql.go": "",
+		})
+		assertModuleResults(t, filepath.Join(dir, "repo/internal/db/sql.go"), "example.com/repo/internal/db")
+		assertModuleResults(t, filepath.Join(dir, "repo/main.go"), "example.com/repo")
+	})
+	t.Run("ModulePrefix", func(t *testing.T) {
+		config := defaultConfig()
+		config.Modules.ModulePrefix = "github.com/a/b"
+		assertConfiguredModuleResults(t, config, "cmd/tool/main.go", "github.com/a/b/cmd/tool")
+		assertConfiguredModuleResults(t, config, "github.com/a/b/cmd/tool", "cmd/tool")
+	})
+	t.Run("NoPrefix", func(t *testing.T) {
+		_, err := moduleCommand(defaultConfig(), []string{"cmd/tool/main.go"})
+		if err == nil || err.Error() != "Set modules.module_prefix to convert relative Go paths" {
+			t.Fatalf("Got %v expected a missing prefix error", err)
+		}
+	})
+}
+
+func TestOtherModules(t *testing.T) {
+	cases := map[string]string{
+		"src/main/java/com/example/App.java":    "com.example.App",
+		"app/src/test/kotlin/com/ex/Spec.kt":    "com.ex.Spec",
+		"java com.example.App":                  "com/example/App.java, com/example/App.kt",
+		"src/net/http.rs":                       "crate::net::http",
+		"src/net/mod.rs":                        "crate::net",
+		"src/lib.rs":                            "crate",
+		"crate::net::http":                      "net/http.rs, net/http/mod.rs",
+		"src/components/Button.tsx":             "./src/components/Button",
+		"src/lib/index.ts from src/app/main.ts": "../lib",
+		"src/app/util.js from src/app/main.js":  "./util",
+		"../lib from src/app/main.js":           "src/lib.js, src/lib/index.js",
+		"ts ./util":                             "util.ts, util/index.ts",
+	}
+	for query, expected := range cases {
+		t.Run(query, func(t *testing.T) {
+			if strings.HasPrefix(query, "app/") {
+				// Absolute paths are cut after the last source root
+				query = "/home/" + query
+			}
+			assertModuleResults(t, query, expected)
+		})
+	}
+}
+
+func TestModulesConfig(t *testing.T) {
+	config, err := loadTestConfig(t, `{"modules": {"source_roots": {"python": ["lib"]}}}`, map[string]string{"RALPHRED_MODULE_PREFIX": "example.com/x"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if config.Modules.ModulePrefix != "example.com/x" {
+		t.Fatalf("Got %s expected the env prefix", config.Modules.ModulePrefix)
+	}
+	assertConfigError(t, `{"modules": {"source_roots": {"py": ["src"]}}}`, nil, `modules.source_roots has an unknown language "py"`)
+	assertConfigError(t, `{"modules": {"source_roots": {"python": ["/src"]}}}`, nil, "modules.source_roots.python must be relative paths")
+}
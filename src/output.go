@@ -0,0 +1,156 @@
+package ralphred
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const DefaultOutputFormat = "alfred"
+
+type OutputFormat struct {
+	Description string
+	Render      func(io.Writer, AlfredResponse) error
+}
+
+var output_renderers = map[string]OutputFormat{
+	"alfred": {
+		Description: "Alfred script filter JSON",
+		Render:      renderAlfred,
+	},
+	"text": {
+		Description: "One title per line",
+		Render:      renderText,
+	},
+	"tsv": {
+		Description: "Tab separated title, subtitle and arg",
+		Render:      renderTSV,
+	},
+	"rofi": {
+		Description: "Rofi script mode rows with the arg in ROFI_INFO",
+		Render:      renderRofi,
+	},
+	"jsonl": {
+		Description: "One JSON encoded item per line",
+		Render:      renderJSONLines,
+	},
+}
+
+// OutputFormatNames returns the names that can be passed to --format
+func OutputFormatNames() []string {
+	names := make([]string, 0, len(output_renderers))
+	for name := range output_renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupOutputFormat(name string) (OutputFormat, error) {
+	if name == "" {
+		name = DefaultOutputFormat
+	}
+	format, exists := output_renderers[name]
+	if !exists {
+		return OutputFormat{}, fmt.Errorf(
+			"Unknown output format \"%s\", expected one of: %s",
+			name,
+			strings.Join(OutputFormatNames(), ", "),
+		)
+	}
+	return format, nil
+}
+
+// Line based formats can't have line breaks or tabs in a field
+func singleLine(str string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(str)
+}
+
+func itemArg(item AlfredItem) string {
+	return strings.Join(item.Arg, " ")
+}
+
+func renderAlfred(w io.Writer, resp AlfredResponse) error {
+	json_data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(json_data))
+	return err
+}
+
+func renderText(w io.Writer, resp AlfredResponse) error {
+	for _, item := range resp.Items {
+		_, err := fmt.Fprintln(w, singleLine(item.Title))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderTSV(w io.Writer, resp AlfredResponse) error {
+	for _, item := range resp.Items {
+		_, err := fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\n",
+			singleLine(item.Title),
+			singleLine(item.Subtitle),
+			singleLine(itemArg(item)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var rofiMarkupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Rows follow rofi's script mode protocol. Options for a row come after a
+// null byte and are separated by the unit separator
+func renderRofi(w io.Writer, resp AlfredResponse) error {
+	_, err := fmt.Fprint(w, "\x00markup-rows\x1ftrue\n")
+	if err != nil {
+		return err
+	}
+
+	for _, item := range resp.Items {
+		display := fmt.Sprintf("<b>%s</b>", rofiMarkupEscaper.Replace(singleLine(item.Title)))
+		if item.Subtitle != "" {
+			display += fmt.Sprintf(" <small>%s</small>", rofiMarkupEscaper.Replace(singleLine(item.Subtitle)))
+		}
+
+		options := []string{
+			"info\x1f" + singleLine(itemArg(item)),
+		}
+		if item.Subtitle != "" {
+			options = append(options, "meta\x1f"+singleLine(item.Subtitle))
+		}
+		if item.Icon != nil && item.Icon.Type == "" {
+			options = append(options, "icon\x1f"+item.Icon.Path)
+		}
+		if item.Valid != nil && !*item.Valid {
+			options = append(options, "nonselectable\x1ftrue")
+		}
+
+		_, err := fmt.Fprintf(w, "%s\x00%s\n", display, strings.Join(options, "\x1f"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderJSONLines(w io.Writer, resp AlfredResponse) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range resp.Items {
+		err := encoder.Encode(item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
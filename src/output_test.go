@@ -0,0 +1,61 @@
+package ralphred
+
+import (
+	"bytes"
+	"testing"
+)
+
+func assertRendered(t *testing.T, format string, resp AlfredResponse, expected string) {
+	t.Helper()
+	outputFormat, err := lookupOutputFormat(format)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	var buf bytes.Buffer
+	err = outputFormat.Render(&buf, resp)
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("Got %q expected %q", buf.String(), expected)
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	resp := AlfredResponse{
+		Items: []AlfredItem{
+			alfredItemFromString("first", false).withSubtitle("sub\ttitle"),
+			alfredItemFromString("a <b>", false).withValid(false),
+		},
+	}
+	t.Run("Alfred", func(t *testing.T) {
+		assertRendered(t, "alfred", resp, `{"items":[{"title":"first","subtitle":"sub\ttitle","arg":["first"],"autocomplete":"first"},{"title":"a \u003cb\u003e","arg":["a \u003cb\u003e"],"autocomplete":"a \u003cb\u003e","valid":false}]}`+"\n")
+	})
+	t.Run("DefaultIsAlfred", func(t *testing.T) {
+		assertRendered(t, "", AlfredResponse{Items: []AlfredItem{}}, "{\"items\":[]}\n")
+	})
+	t.Run("Text", func(t *testing.T) {
+		assertRendered(t, "text", resp, "first\na <b>\n")
+	})
+	t.Run("TSV", func(t *testing.T) {
+		assertRendered(t, "tsv", resp, "first\tsub title\tfirst\na <b>\t\ta <b>\n")
+	})
+	t.Run("Rofi", func(t *testing.T) {
+		assertRendered(
+			t,
+			"rofi",
+			resp,
+			"\x00markup-rows\x1ftrue\n"+
+				"<b>first</b> <small>sub title</small>\x00info\x1ffirst\x1fmeta\x1fsub title\n"+
+				"<b>a &lt;b&gt;</b>\x00info\x1fa <b>\x1fnonselectable\x1ftrue\n",
+		)
+	})
+	t.Run("JSONLines", func(t *testing.T) {
+		assertRendered(t, "jsonl", resp, `{"title":"first","subtitle":"sub\ttitle","arg":["first"],"autocomplete":"first"}`+"\n"+`{"title":"a \u003cb\u003e","arg":["a \u003cb\u003e"],"autocomplete":"a \u003cb\u003e","valid":false}`+"\n")
+	})
+	t.Run("UnknownFormat", func(t *testing.T) {
+		if _, err := lookupOutputFormat("xml"); err == nil {
+			t.Fatal("Expected error, but didn't get one")
+		}
+	})
+}
@@ -0,0 +1,340 @@
+package ralphred
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+//go:embed wordlist.txt
+var passphrase_wordlist_data string
+
+var passphrase_wordlist = strings.Fields(passphrase_wordlist_data)
+
+type passwordClass struct {
+	Name       string
+	Characters string
+}
+
+// Symbols leave out quotes, backslash and backtick, which need escaping in
+// most config files and shells
+var password_classes = []passwordClass{
+	{"upper", "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+	{"lower", "abcdefghijklmnopqrstuvwxyz"},
+	{"digits", "0123456789"},
+	{"symbols", "!#$%&()*+,-./:;<=>?@[]^_{|}~"},
+}
+
+// Characters that are easy to mix up when reading a password
+const ambiguousCharacters = "Il1|O0o"
+
+const (
+	defaultPasswordLength  = 20
+	maxPasswordLength      = 1024
+	defaultPassphraseWords = 6
+	maxPassphraseWords     = 64
+	// Each generation gives this many to pick from
+	passwordCandidates = 5
+)
+
+type passwordOptions struct {
+	Length int
+	// Minimum count of each included class, 0 includes it without requiring it
+	Required         map[string]int
+	ExcludeAmbiguous bool
+}
+
+type passphraseOptions struct {
+	Words          int
+	Separator      string
+	Capitalization string
+}
+
+func randomIndex(n int) (int, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(index.Int64()), nil
+}
+
+func withoutAmbiguous(characters string) string {
+	return strings.Map(func(char rune) rune {
+		if strings.ContainsRune(ambiguousCharacters, char) {
+			return -1
+		}
+		return char
+	}, characters)
+}
+
+func (options passwordOptions) classCharacters() map[string]string {
+	characters := map[string]string{}
+	for _, class := range password_classes {
+		if _, included := options.Required[class.Name]; !included {
+			continue
+		}
+		characters[class.Name] = class.Characters
+		if options.ExcludeAmbiguous {
+			characters[class.Name] = withoutAmbiguous(class.Characters)
+		}
+	}
+	return characters
+}
+
+func (options passwordOptions) alphabet() string {
+	alphabet := ""
+	characters := options.classCharacters()
+	for _, class := range password_classes {
+		alphabet += characters[class.Name]
+	}
+	return alphabet
+}
+
+// Required characters are picked from their class and the rest from the
+// whole alphabet. Where they end up adds a bit more, which isn't counted
+func (options passwordOptions) entropy() float64 {
+	bits := 0.0
+	remaining := options.Length
+	for name, characters := range options.classCharacters() {
+		bits += float64(options.Required[name]) * math.Log2(float64(len(characters)))
+		remaining -= options.Required[name]
+	}
+	return bits + float64(remaining)*math.Log2(float64(len(options.alphabet())))
+}
+
+func randomCharacters(password []byte, characters string) error {
+	for i := range password {
+		index, err := randomIndex(len(characters))
+		if err != nil {
+			return err
+		}
+		password[i] = characters[index]
+	}
+	return nil
+}
+
+// Pick the required characters of each class first, fill the rest from the
+// whole alphabet, then shuffle so the required ones aren't at the start
+func generatePassword(options passwordOptions) (string, error) {
+	password := make([]byte, 0, options.Length)
+	characters := options.classCharacters()
+	for _, class := range password_classes {
+		required := make([]byte, options.Required[class.Name])
+		if err := randomCharacters(required, characters[class.Name]); err != nil {
+			return "", err
+		}
+		password = append(password, required...)
+	}
+	rest := make([]byte, options.Length-len(password))
+	if err := randomCharacters(rest, options.alphabet()); err != nil {
+		return "", err
+	}
+	password = append(password, rest...)
+
+	// Fisher-Yates
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+func capitalizeWord(word string, capitalization string) string {
+	switch capitalization {
+	case "upper":
+		return strings.ToUpper(word)
+	case "title":
+		return strings.ToUpper(word[:1]) + word[1:]
+	}
+	return word
+}
+
+func generatePassphrase(options passphraseOptions) (string, error) {
+	words := make([]string, options.Words)
+	for i := range words {
+		index, err := randomIndex(len(passphrase_wordlist))
+		if err != nil {
+			return "", err
+		}
+		words[i] = capitalizeWord(passphrase_wordlist[index], options.Capitalization)
+	}
+	return strings.Join(words, options.Separator), nil
+}
+
+func (options passphraseOptions) entropy() float64 {
+	return float64(options.Words) * math.Log2(float64(len(passphrase_wordlist)))
+}
+
+// Whether the arg is a count, and an error if it's out of range
+func parsePasswordCount(label string, arg string, max int) (int, bool, error) {
+	count, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, false, nil
+	}
+	if count <= 0 || count > max {
+		return 0, true, fmt.Errorf("%s must be a number from 1 to %d", label, max)
+	}
+	return count, true, nil
+}
+
+func isPasswordClass(name string) bool {
+	for _, class := range password_classes {
+		if class.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Options are a length, class names to only use those classes, -class to
+// leave one out, class=N to require at least N of a class (0 to not require
+// it) and unambiguous. Every included class is required once by default
+func parsePasswordOptions(args []string) (passwordOptions, error) {
+	options := passwordOptions{Length: defaultPasswordLength, Required: map[string]int{}}
+	selected := map[string]bool{}
+	excluded := map[string]bool{}
+	counts := map[string]int{}
+	for _, arg := range args {
+		if count, isCount, err := parsePasswordCount("Length", arg, maxPasswordLength); isCount {
+			if err != nil {
+				return options, err
+			}
+			options.Length = count
+			continue
+		}
+
+		parts := strings.SplitN(arg, "=", 2)
+		switch {
+		case arg == "unambiguous":
+			options.ExcludeAmbiguous = true
+		case strings.HasPrefix(arg, "-") && isPasswordClass(arg[1:]):
+			excluded[arg[1:]] = true
+		case len(parts) == 2 && isPasswordClass(parts[0]):
+			count, err := strconv.Atoi(parts[1])
+			if err != nil || count < 0 {
+				return options, fmt.Errorf("Expected a count for %s got %s", parts[0], parts[1])
+			}
+			counts[parts[0]] = count
+		case isPasswordClass(arg):
+			selected[arg] = true
+		default:
+			return options, fmt.Errorf("Unknown option %s", arg)
+		}
+	}
+
+	for _, class := range password_classes {
+		_, counted := counts[class.Name]
+		included := selected[class.Name] || counted || len(selected) == 0
+		if !included || excluded[class.Name] {
+			continue
+		}
+		options.Required[class.Name] = 1
+		if counted {
+			options.Required[class.Name] = counts[class.Name]
+		}
+	}
+	if len(options.Required) == 0 {
+		return options, errors.New("At least one character class is needed")
+	}
+
+	requiredTotal := 0
+	for _, count := range options.Required {
+		requiredTotal += count
+	}
+	if requiredTotal > options.Length {
+		return options, fmt.Errorf("The required characters don't fit in %s", pluralize(options.Length, "character"))
+	}
+	return options, nil
+}
+
+// Options are a word count, sep=X for the separator (space for a space) and
+// lower, upper or title for the capitalization
+func parsePassphraseOptions(args []string) (passphraseOptions, error) {
+	options := passphraseOptions{Words: defaultPassphraseWords, Separator: "-", Capitalization: "lower"}
+	for _, arg := range args {
+		if count, isCount, err := parsePasswordCount("Word count", arg, maxPassphraseWords); isCount {
+			if err != nil {
+				return options, err
+			}
+			options.Words = count
+			continue
+		}
+		switch {
+		case strings.HasPrefix(arg, "sep="):
+			options.Separator = strings.TrimPrefix(arg, "sep=")
+			if options.Separator == "space" {
+				options.Separator = " "
+			}
+		case arg == "lower" || arg == "upper" || arg == "title":
+			options.Capitalization = arg
+		default:
+			return options, fmt.Errorf("Unknown option %s", arg)
+		}
+	}
+	return options, nil
+}
+
+func passwordItems(generate func() (string, error), subtitle string) ([]AlfredItem, error) {
+	items := make([]AlfredItem, passwordCandidates)
+	for i := range items {
+		password, err := generate()
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		items[i] = alfredItemFromString(password, false).withSubtitle(subtitle)
+	}
+	return items, nil
+}
+
+func passwordClassNames(options passwordOptions) string {
+	names := []string{}
+	for _, class := range password_classes {
+		if count, included := options.Required[class.Name]; included {
+			if count != 1 {
+				names = append(names, fmt.Sprintf("%s=%d", class.Name, count))
+			} else {
+				names = append(names, class.Name)
+			}
+		}
+	}
+	if options.ExcludeAmbiguous {
+		names = append(names, "unambiguous")
+	}
+	return strings.Join(names, ", ")
+}
+
+func passwordCommand(args []string) ([]AlfredItem, error) {
+	if len(args) > 0 && (args[0] == "phrase" || args[0] == "passphrase") {
+		options, err := parsePassphraseOptions(args[1:])
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		subtitle := fmt.Sprintf(
+			"%.0f bits of entropy, %s from a list of %d",
+			options.entropy(),
+			pluralize(options.Words, "word"),
+			len(passphrase_wordlist),
+		)
+		return passwordItems(func() (string, error) { return generatePassphrase(options) }, subtitle)
+	}
+
+	options, err := parsePasswordOptions(args)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	subtitle := fmt.Sprintf(
+		"At least %.0f bits of entropy, %s of %s",
+		options.entropy(),
+		pluralize(options.Length, "character"),
+		passwordClassNames(options),
+	)
+	return passwordItems(func() (string, error) { return generatePassword(options) }, subtitle)
+}
@@ -0,0 +1,111 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordOptions(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		options, err := parsePasswordOptions([]string{})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if options.Length != 20 || len(options.alphabet()) != 26+26+10+28 {
+			t.Fatalf("Got length %d with %d characters", options.Length, len(options.alphabet()))
+		}
+	})
+	t.Run("OnlyClasses", func(t *testing.T) {
+		options, _ := parsePasswordOptions([]string{"12", "lower", "digits"})
+		if options.alphabet() != "abcdefghijklmnopqrstuvwxyz0123456789" {
+			t.Fatalf("Got %s", options.alphabet())
+		}
+	})
+	t.Run("ExcludeClassAndAmbiguous", func(t *testing.T) {
+		options, _ := parsePasswordOptions([]string{"-symbols", "unambiguous"})
+		if strings.ContainsAny(options.alphabet(), "Il1O0o!") {
+			t.Fatalf("Got %s", options.alphabet())
+		}
+	})
+	t.Run("RequiredCountKeepsOtherClasses", func(t *testing.T) {
+		options, _ := parsePasswordOptions([]string{"digits=3", "symbols=0"})
+		if len(options.Required) != 4 || options.Required["digits"] != 3 || options.Required["symbols"] != 0 {
+			t.Fatalf("Got %v", options.Required)
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]string{
+			"0":                              "Length must be a number from 1 to 1024",
+			"-upper -lower -digits -symbols": "At least one character class is needed",
+			"4 digits=5":                     "The required characters don't fit in 4 characters",
+			"digits=x":                       "Expected a count for digits got x",
+			"emoji":                          "Unknown option emoji",
+		}
+		for query, expected := range cases {
+			_, err := parsePasswordOptions(strings.Fields(query))
+			if err == nil || err.Error() != expected {
+				t.Fatalf("Got %v expected %s", err, expected)
+			}
+		}
+	})
+}
+
+func assertGeneratedPasswords(t *testing.T, query string, length int, digits int) {
+	t.Helper()
+	options, err := parsePasswordOptions(strings.Fields(query))
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	for i := 0; i < 20; i++ {
+		password, err := generatePassword(options)
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		others := strings.Trim(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+		digitCount := 0
+		for _, char := range password {
+			if char >= '0' && char <= '9' {
+				digitCount++
+			}
+		}
+		if len(password) != length || others != "" || digitCount < digits {
+			t.Fatalf("Got %s expected %d upper case letters and digits with %d digits", password, length, digits)
+		}
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	t.Run("RequiredCount", func(t *testing.T) {
+		assertGeneratedPasswords(t, "8 upper digits=4", 8, 4)
+	})
+	t.Run("HighRequiredCount", func(t *testing.T) {
+		assertGeneratedPasswords(t, "12 upper digits=11", 12, 11)
+	})
+}
+
+func TestPassphrase(t *testing.T) {
+	items, err := passwordCommand([]string{"phrase", "4", "sep=space", "title"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if len(items) != passwordCandidates {
+		t.Fatalf("Got %d candidates", len(items))
+	}
+	words := strings.Split(items[0].Title, " ")
+	if len(words) != 4 || strings.ToUpper(words[0][:1]) != words[0][:1] {
+		t.Fatalf("Got %s expected 4 title case words", items[0].Title)
+	}
+	if items[0].Subtitle != "43 bits of entropy, 4 words from a list of 1736" {
+		t.Fatalf("Got %s", items[0].Subtitle)
+	}
+}
+
+func TestPasswordCommand(t *testing.T) {
+	items, err := passwordCommand([]string{"16", "lower", "digits"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Subtitle != "At least 80 bits of entropy, 16 characters of lower, digits" {
+		t.Fatalf("Got %s", items[0].Subtitle)
+	}
+}
@@ -0,0 +1,174 @@
+package ralphred
+
+import (
+	"io"
+	"strings"
+)
+
+type queryToken struct {
+	Value string
+	// Byte offsets of the raw token in the query, including any quotes
+	Start int
+	End   int
+	// Whether any part of the token was quoted or escaped
+	Quoted bool
+}
+
+func isQueryWhitespace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
+}
+
+// Split the query into tokens the way a POSIX shell would. Single quotes keep
+// everything literally, double quotes allow escaping `"` and `\`, and a
+// backslash outside of quotes escapes the next character. Alfred sends the
+// query on every keystroke, so an unterminated quote runs to the end of the
+// query instead of being an error
+func tokenizeQuery(query string) []queryToken {
+	tokens := []queryToken{}
+	var current strings.Builder
+	inToken := false
+	token := queryToken{}
+
+	endToken := func(end int) {
+		if inToken {
+			token.Value = current.String()
+			token.End = end
+			tokens = append(tokens, token)
+		}
+		current.Reset()
+		inToken = false
+		token = queryToken{}
+	}
+	startToken := func(start int) {
+		if !inToken {
+			inToken = true
+			token.Start = start
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		char := query[i]
+		switch {
+		case isQueryWhitespace(char):
+			endToken(i)
+		case char == '\\':
+			startToken(i)
+			token.Quoted = true
+			if i+1 < len(query) {
+				i++
+				current.WriteByte(query[i])
+			}
+		case char == '\'':
+			startToken(i)
+			token.Quoted = true
+			end := strings.IndexByte(query[i+1:], '\'')
+			if end == -1 {
+				current.WriteString(query[i+1:])
+				i = len(query)
+			} else {
+				current.WriteString(query[i+1 : i+1+end])
+				i += end + 1
+			}
+		case char == '"':
+			startToken(i)
+			token.Quoted = true
+			i++
+			for ; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' && i+1 < len(query) && (query[i+1] == '"' || query[i+1] == '\\') {
+					i++
+				}
+				current.WriteByte(query[i])
+			}
+		default:
+			startToken(i)
+			current.WriteByte(char)
+		}
+	}
+	endToken(len(query))
+	return tokens
+}
+
+// CommandRequest is what a command gets from the query alfred sent
+type CommandRequest struct {
+	// The query exactly as it was typed
+	Query string
+	Args  []string
+	// Settings from the config file and environment
+	Config *Config
+	// Where the command can store things on disk
+	Env Environment
+	// Input piped to ralphred, nil when there isn't any. Nothing reads it
+	// unless the command needs it
+	Stdin  io.Reader
+	tokens []queryToken
+}
+
+func newCommandRequest(query string) CommandRequest {
+	tokens := tokenizeQuery(query)
+	args := make([]string, len(tokens))
+	for i, token := range tokens {
+		args[i] = token.Value
+	}
+	return CommandRequest{
+		Query:  query,
+		Args:   args,
+		Config: defaultConfig(),
+		tokens: tokens,
+	}
+}
+
+// RawAfter returns the query as typed after the first n args, so whitespace
+// between the remaining args is kept. If the rest is a single quoted arg the
+// value of the arg is used instead
+func (req CommandRequest) RawAfter(n int) string {
+	if n >= len(req.tokens) {
+		return ""
+	}
+	last := len(req.tokens) - 1
+	if n == last && req.tokens[n].Quoted {
+		return req.tokens[n].Value
+	}
+	return req.Query[req.tokens[n].Start:req.tokens[last].End]
+}
+
+// RawArg returns the nth arg exactly as it was typed, quotes included
+func (req CommandRequest) RawArg(n int) string {
+	if n >= len(req.tokens) {
+		return ""
+	}
+	return req.Query[req.tokens[n].Start:req.tokens[n].End]
+}
+
+// RawFrom returns the query from the nth arg on exactly as it was typed.
+// Unlike RawAfter quotes are always kept, which is what's wanted for input
+// that uses quotes itself, like JSON
+func (req CommandRequest) RawFrom(n int) string {
+	if n >= len(req.tokens) {
+		return ""
+	}
+	return req.Query[req.tokens[n].Start:req.tokens[len(req.tokens)-1].End]
+}
+
+// Remove one pair of quotes from around str. Only quote characters in quotes
+// are removed, and only when the pair wraps all of str
+func trimQuotePair(str string, quotes string) string {
+	if len(str) < 2 || str[0] != str[len(str)-1] || !strings.ContainsRune(quotes, rune(str[0])) {
+		return str
+	}
+	inner := str[1 : len(str)-1]
+	if strings.IndexByte(inner, str[0]) != -1 {
+		return str
+	}
+	return inner
+}
+
+// ArgsWithRawRest returns the first n args followed by the rest of the query
+// as a single arg
+func (req CommandRequest) ArgsWithRawRest(n int) []string {
+	if n >= len(req.Args) {
+		return req.Args
+	}
+	args := make([]string, n, n+1)
+	copy(args, req.Args[:n])
+	return append(args, req.RawAfter(n))
+}
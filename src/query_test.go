@@ -0,0 +1,98 @@
+package ralphred
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	assertArgs := func(t *testing.T, query string, expected []string) {
+		t.Helper()
+		result := newCommandRequest(query).Args
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("Got %q expected %q", result, expected)
+		}
+	}
+	t.Run("Empty", func(t *testing.T) {
+		assertArgs(t, "", []string{})
+	})
+	t.Run("ExtraWhitespace", func(t *testing.T) {
+		assertArgs(t, " a  b\tc\nd ", []string{"a", "b", "c", "d"})
+	})
+	t.Run("DoubleQuotes", func(t *testing.T) {
+		assertArgs(t, `length "a  b"`, []string{"length", "a  b"})
+	})
+	t.Run("SingleQuotes", func(t *testing.T) {
+		assertArgs(t, `'a "b" \c'`, []string{`a "b" \c`})
+	})
+	t.Run("EscapedQuoteInDoubleQuotes", func(t *testing.T) {
+		assertArgs(t, `"a \"b\" \c"`, []string{`a "b" \c`})
+	})
+	t.Run("BackslashEscape", func(t *testing.T) {
+		assertArgs(t, `a\ b c`, []string{"a b", "c"})
+	})
+	t.Run("JoinedQuotes", func(t *testing.T) {
+		assertArgs(t, `a"b c"'d'`, []string{"ab cd"})
+	})
+	t.Run("EmptyQuotes", func(t *testing.T) {
+		assertArgs(t, `a ""`, []string{"a", ""})
+	})
+	t.Run("UnterminatedQuote", func(t *testing.T) {
+		assertArgs(t, `a "b c`, []string{"a", "b c"})
+	})
+}
+
+func TestRawAfter(t *testing.T) {
+	assertRaw := func(t *testing.T, query string, n int, expected string) {
+		t.Helper()
+		result := newCommandRequest(query).RawAfter(n)
+		if result != expected {
+			t.Fatalf("Got %q expected %q", result, expected)
+		}
+	}
+	t.Run("KeepsWhitespace", func(t *testing.T) {
+		assertRaw(t, "length a  b ", 1, "a  b")
+	})
+	t.Run("SingleQuotedArg", func(t *testing.T) {
+		assertRaw(t, `length "a  b"`, 1, "a  b")
+	})
+	t.Run("NothingLeft", func(t *testing.T) {
+		assertRaw(t, "length", 1, "")
+	})
+}
+
+func TestRawFrom(t *testing.T) {
+	t.Run("KeepsQuotes", func(t *testing.T) {
+		result := newCommandRequest(`minify {"a": "b c"} `).RawFrom(1)
+		if result != `{"a": "b c"}` {
+			t.Fatalf("Got %q expected %q", result, `{"a": "b c"}`)
+		}
+	})
+	t.Run("RawArg", func(t *testing.T) {
+		result := newCommandRequest(`path ."a b" {}`).RawArg(1)
+		if result != `."a b"` {
+			t.Fatalf("Got %q expected %q", result, `."a b"`)
+		}
+	})
+}
+
+func TestTrimQuotePair(t *testing.T) {
+	tests := []struct {
+		input    string
+		quotes   string
+		expected string
+	}{
+		{input: `'.a'`, quotes: `'"`, expected: ".a"},
+		{input: `".a"`, quotes: `'"`, expected: ".a"},
+		{input: `".a"`, quotes: "'", expected: `".a"`},
+		{input: `."a"`, quotes: `'"`, expected: `."a"`},
+		{input: `'a'.'b'`, quotes: `'"`, expected: `'a'.'b'`},
+		{input: `'`, quotes: `'"`, expected: `'`},
+	}
+	for _, test := range tests {
+		result := trimQuotePair(test.input, test.quotes)
+		if result != test.expected {
+			t.Fatalf("Got %q expected %q", result, test.expected)
+		}
+	}
+}
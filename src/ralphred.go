@@ -1,50 +1,68 @@
 package ralphred
 
 import (
+	"io"
 	"log"
+	"os"
 	"strings"
 )
 
-func extract_args(query string) []string {
-	if query == "" {
-		return []string{}
-	}
+type RunOptions struct {
+	// Name of the output format, defaults to DefaultOutputFormat
+	Format string
+	// Where the rendered response is written, defaults to stdout
+	Output io.Writer
+	// Looks up environment variables, defaults to os.Getenv
+	Getenv func(string) string
+	// Input for commands that read stdin, leave nil when nothing is piped
+	Stdin io.Reader
+}
 
-	args := []string{}
-	args_raw := strings.Split(query, " ")
-	for _, arg := range args_raw {
-		if arg != "" {
-			args = append(args, arg)
-		}
+func Run(cmd string, query string) {
+	err := RunWithOptions(cmd, query, RunOptions{})
+	if err != nil {
+		log.Println(err)
 	}
-	return args
 }
 
-func Run(cmd string, query string) {
-	args := extract_args(query)
-	if query != "" {
+// RunWithOptions runs the command and renders the response with the
+// requested output format. Errors from the command become error items, so
+// only problems with rendering the output are returned
+func RunWithOptions(cmd string, query string, opts RunOptions) error {
+	format, err := lookupOutputFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
 	}
-	log.Printf("cmd: %s, args: [%s]\n", cmd, strings.Join(args, ", "))
 
-	var items []AlfredItem
-	var err error
-
-	switch cmd {
-	case "strings":
-		items, err = stringCommand(args)
-	case "convert":
-		items, err = convertCommand(args)
-	case "datetimemath":
-		items, err = dateTimeMathCommand(args)
-	case "devdocs":
-		items, err = devdocsCommand(args)
-	case "devdocs_docset":
-		items, err = devdocsDocSetCommand(args)
+	getenv := opts.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
 	}
 
+	req := newCommandRequest(query)
+	req.Stdin = opts.Stdin
+	log.Printf("cmd: %s, args: [%s]\n", cmd, strings.Join(req.Args, ", "))
+
+	var items []AlfredItem
+	env, err := loadEnvironment(getenv)
+	if err == nil {
+		req.Env = env
+		env.debugf("Environment: %+v", env)
+
+		var config *Config
+		config, err = loadConfig(env, getenv)
+		if err == nil {
+			req.Config = config
+			items, err = commandRegistry.Run(cmd, req)
+		}
+	}
 	if err != nil {
 		items = errorAlfredItems(err.Error())
 	}
 	resp := AlfredResponse{Items: items}
-	resp.Print()
+	return format.Render(output, resp)
 }
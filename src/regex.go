@@ -0,0 +1,184 @@
+package ralphred
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+var regex_flags = map[rune]string{
+	'i': "case-insensitive",
+	'm': "multiline",
+	's': "dotall",
+}
+
+var regexFlagsArg = regexp.MustCompile(`^-[ims]+$`)
+
+// Matching a whole document would be too many items for alfred
+const maxRegexMatches = 50
+
+type regexQuery struct {
+	Pattern string
+	// The inline flags to add to the pattern, like "im"
+	Flags       string
+	Replacement *string
+	Text        string
+}
+
+// Backslashes outside of quotes are escapes in the query, but they're
+// wanted in patterns, so unquoted args are used as typed
+func regexArg(req CommandRequest, n int) string {
+	raw := req.RawArg(n)
+	if strings.HasPrefix(raw, "'") || strings.HasPrefix(raw, "\"") {
+		return req.Args[n]
+	}
+	return raw
+}
+
+var regex_usage = []AlfredItem{
+	alfredItemFromString("regex [-ims] <pattern> <text>", false).withSubtitle("List the matches of the pattern in the text").withValid(false),
+	alfredItemFromString("regex [-ims] replace <pattern> <replacement> <text>", false).withSubtitle("Preview replacing the matches, groups are $1 or ${name}").withValid(false),
+}
+
+func parseRegexQuery(req CommandRequest) (regexQuery, bool) {
+	query := regexQuery{}
+	n := 0
+	for ; n < len(req.Args) && regexFlagsArg.MatchString(req.RawArg(n)); n++ {
+		for _, flag := range req.Args[n][1:] {
+			if !strings.ContainsRune(query.Flags, flag) {
+				query.Flags += string(flag)
+			}
+		}
+	}
+
+	replacing := n < len(req.Args) && req.Args[n] == "replace"
+	if replacing {
+		n++
+	}
+	if n >= len(req.Args) {
+		return query, false
+	}
+	query.Pattern = regexArg(req, n)
+	n++
+	if replacing {
+		if n >= len(req.Args) {
+			return query, false
+		}
+		replacement := regexArg(req, n)
+		query.Replacement = &replacement
+		n++
+	}
+	query.Text = req.RawAfter(n)
+	return query, true
+}
+
+// Position in the pattern is 1-based to match the other parse errors. The
+// error only has the bad part of the pattern, so the position is left out
+// when that part is in the pattern more than once
+func compileRegex(pattern string, flags string) (*regexp.Regexp, error) {
+	withFlags := pattern
+	if flags != "" {
+		withFlags = fmt.Sprintf("(?%s)%s", flags, pattern)
+	}
+	compiled, err := regexp.Compile(withFlags)
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		position := strings.Index(pattern, syntaxErr.Expr)
+		if position == -1 || syntaxErr.Expr == "" {
+			return nil, fmt.Errorf("Invalid regex: %s", err)
+		} else if position != strings.LastIndex(pattern, syntaxErr.Expr) {
+			return nil, fmt.Errorf("Invalid regex: %s: `%s`", syntaxErr.Code, syntaxErr.Expr)
+		}
+		return nil, fmt.Errorf("Invalid regex at character %d: %s: `%s`", position+1, syntaxErr.Code, syntaxErr.Expr)
+	} else if err != nil {
+		return nil, fmt.Errorf("Invalid regex: %s", err)
+	}
+	return compiled, nil
+}
+
+func regexFlagNames(flags string) string {
+	names := []string{}
+	for _, flag := range flags {
+		names = append(names, regex_flags[flag])
+	}
+	return strings.Join(names, ", ")
+}
+
+// Empty matches are quoted so there's something to see
+func regexMatchTitle(match string) string {
+	if match == "" {
+		return `""`
+	}
+	return match
+}
+
+func regexGroupName(compiled *regexp.Regexp, group int) string {
+	name := compiled.SubexpNames()[group]
+	if name == "" {
+		return fmt.Sprintf("group %d", group)
+	}
+	return fmt.Sprintf("group %d (%s)", group, name)
+}
+
+// An item for each match followed by an item for each of its groups
+func regexMatchItems(compiled *regexp.Regexp, text string, matches [][]int) []AlfredItem {
+	items := []AlfredItem{}
+	for i, match := range matches {
+		if i == maxRegexMatches {
+			items = append(items, alfredItemFromString(
+				fmt.Sprintf("%d more matches", len(matches)-maxRegexMatches),
+				false,
+			).withValid(false))
+			break
+		}
+		matched := text[match[0]:match[1]]
+		items = append(items, alfredItemFromString(regexMatchTitle(matched), false).
+			withSubtitle(fmt.Sprintf("Match %d, bytes %d-%d", i+1, match[0], match[1])))
+		for group := 1; group <= compiled.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			if start == -1 {
+				items = append(items, alfredItemFromString("Not matched", false).
+					withSubtitle(fmt.Sprintf("Match %d %s", i+1, regexGroupName(compiled, group))).
+					withValid(false))
+				continue
+			}
+			items = append(items, alfredItemFromString(regexMatchTitle(text[start:end]), false).
+				withSubtitle(fmt.Sprintf("Match %d %s, bytes %d-%d", i+1, regexGroupName(compiled, group), start, end)))
+		}
+	}
+	return items
+}
+
+func regexCommand(req CommandRequest) ([]AlfredItem, error) {
+	query, complete := parseRegexQuery(req)
+	if !complete {
+		return regex_usage, nil
+	}
+	compiled, err := compileRegex(query.Pattern, query.Flags)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+
+	matches := compiled.FindAllStringSubmatchIndex(query.Text, -1)
+	subtitle := pluralize(compiled.NumSubexp(), "group")
+	if query.Flags != "" {
+		subtitle = fmt.Sprintf("%s, %s", subtitle, regexFlagNames(query.Flags))
+	}
+
+	var summary AlfredItem
+	if query.Replacement != nil {
+		replaced := compiled.ReplaceAllString(query.Text, *query.Replacement)
+		summary = alfredItemFromString(replaced, false).
+			withSubtitle(fmt.Sprintf("Replaced %s, %s", pluralize(len(matches), "match"), subtitle))
+	} else if len(matches) == 0 {
+		summary = alfredItemFromString("No matches", false).withSubtitle(subtitle).withValid(false)
+	} else {
+		summary = alfredItemFromString(pluralize(len(matches), "match"), false).
+			withSubtitle(subtitle).
+			withValid(false)
+	}
+	summary.UID = "summary"
+	return append([]AlfredItem{summary}, regexMatchItems(compiled, query.Text, matches)...), nil
+}
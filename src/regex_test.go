@@ -0,0 +1,80 @@
+package ralphred
+
+import (
+	"testing"
+)
+
+func TestRegexMatches(t *testing.T) {
+	items, err := runCommandQuery(t, regexCommand, `(?P<key>\w+)=(\d+)? a=1 b=`, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "2 matches" {
+		t.Fatalf("Got %s expected 2 matches", items[0].Title)
+	}
+	expected := []struct {
+		Title    string
+		Subtitle string
+	}{
+		{"a=1", "Match 1, bytes 0-3"},
+		{"a", "Match 1 group 1 (key), bytes 0-1"},
+		{"1", "Match 1 group 2, bytes 2-3"},
+		{"b=", "Match 2, bytes 4-6"},
+		{"b", "Match 2 group 1 (key), bytes 4-5"},
+		{"Not matched", "Match 2 group 2"},
+	}
+	for i, item := range items[1:] {
+		if item.Title != expected[i].Title || item.Subtitle != expected[i].Subtitle {
+			t.Fatalf("Got %s (%s) expected %s (%s)", item.Title, item.Subtitle, expected[i].Title, expected[i].Subtitle)
+		}
+	}
+}
+
+func TestRegexFlags(t *testing.T) {
+	items, err := runCommandQuery(t, regexCommand, `-i -ms ^a.b$ 'x
+A
+b'`, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "1 match" {
+		t.Fatalf("Got %s expected 1 match", items[0].Title)
+	}
+	if items[0].Subtitle != "0 groups, case-insensitive, multiline, dotall" {
+		t.Fatalf("Got %s", items[0].Subtitle)
+	}
+	if items[1].Subtitle != "Match 1, bytes 2-5" {
+		t.Fatalf("Got %s", items[1].Subtitle)
+	}
+}
+
+func TestRegexReplace(t *testing.T) {
+	items, err := runCommandQuery(t, regexCommand, `replace '(\w+)@(?P<domain>\w+)' '${domain}: $1' ada@example, bob@test`, "")
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "example: ada, test: bob" {
+		t.Fatalf("Got %s", items[0].Title)
+	}
+	if items[0].Subtitle != "Replaced 2 matches, 2 groups" {
+		t.Fatalf("Got %s", items[0].Subtitle)
+	}
+}
+
+func TestRegexCompileError(t *testing.T) {
+	cases := map[string]string{
+		`ab[c text`:    "Invalid regex at character 3: missing closing ]: `[c`",
+		`a(?z)b text`:  "Invalid regex at character 2: invalid or unsupported Perl syntax: `(?z`",
+		`-i a** text`:  "Invalid regex at character 2: invalid nested repetition operator: `**`",
+		`(?z|(?z text`: "Invalid regex: invalid or unsupported Perl syntax: `(?z`",
+		`a*** text`:    "Invalid regex: invalid nested repetition operator: `**`",
+	}
+	for query, expected := range cases {
+		t.Run(query, func(t *testing.T) {
+			_, err := runCommandQuery(t, regexCommand, query, "")
+			if err == nil || err.Error() != expected {
+				t.Fatalf("Got %v expected %s", err, expected)
+			}
+		})
+	}
+}
@@ -1,125 +1,261 @@
 package ralphred
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
 	"fmt"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 type StringConversion struct {
 	Description string
-	Convert     func(string) string
+	Convert     func(string) (string, error)
+	// Set instead of Convert by conversions that depend on the config
+	ConvertWithConfig func(config *Config, input_string string) (string, error)
+	// Left out of previews, for conversions that give a result for almost
+	// any string or look at the disk
+	NoPreview bool
+}
+
+func (conversion StringConversion) run(config *Config, input_string string) (string, error) {
+	if conversion.ConvertWithConfig != nil {
+		return conversion.ConvertWithConfig(config, input_string)
+	}
+	return conversion.Convert(input_string)
 }
 
 var string_conversions = map[string]StringConversion{
 	"length": {
-		Description: "Return the length of the string",
-		Convert: func(input_string string) string {
-			return fmt.Sprintf("%d", len(input_string))
+		Description: "Return the number of characters in the string",
+		Convert: func(input_string string) (string, error) {
+			return fmt.Sprintf("%d", len(splitGraphemes(input_string))), nil
 		},
 	},
 	"words": {
 		Description: "Return the number of words in the string",
-		Convert: func(input_string string) string {
-			return fmt.Sprintf("%d", len(strings.Fields(input_string)))
+		Convert: func(input_string string) (string, error) {
+			return fmt.Sprintf("%d", len(strings.Fields(input_string))), nil
 		},
 	},
 	"lower": {
 		Description: "Return the string lower cased",
-		Convert: func(input_string string) string {
-			return strings.ToLower(input_string)
+		Convert: func(input_string string) (string, error) {
+			return strings.ToLower(input_string), nil
 		},
 	},
 	"title": {
 		Description: "Return the string title cased",
-		Convert: func(input_string string) string {
-			return strings.Title(strings.ToLower(input_string))
+		Convert: func(input_string string) (string, error) {
+			return strings.Title(strings.ToLower(input_string)), nil
 		},
 	},
 	"upper": {
 		Description: "Return the string upper cased",
-		Convert: func(input_string string) string {
-			return strings.ToUpper(input_string)
-		},
-	},
-	"pymod": {
-		Description: "Convert filepath to python module path",
-		Convert: func(input_string string) string {
-			no_slashes := strings.Replace(input_string, "/", ".", -1)
-			return strings.TrimSuffix(no_slashes, ".py")
-		},
-	},
-	"unpymod": {
-		Description: "Convert python module path to filepath",
-		Convert: func(input_string string) string {
-			return strings.Replace(input_string, ".", "/", -1) + ".py"
-		},
-	},
-	"md5": {
-		Description: "Return md5 hash of the string",
-		Convert: func(input_string string) string {
-			return hashString(md5.New(), input_string)
-		},
-	},
-	"sha1": {
-		Description: "Return sha1 hash of the string",
-		Convert: func(input_string string) string {
-			return hashString(sha1.New(), input_string)
-		},
-	},
-	"sha256": {
-		Description: "Return sha256 hash of the string",
-		Convert: func(input_string string) string {
-			return hashString(sha256.New(), input_string)
-		},
-	},
-	"sha512": {
-		Description: "Return 512 hash of the string",
-		Convert: func(input_string string) string {
-			return hashString(sha512.New(), input_string)
+		Convert: func(input_string string) (string, error) {
+			return strings.ToUpper(input_string), nil
 		},
 	},
 }
 
-func stringCommands() []AlfredItem {
-	helpText := make([]AlfredItem, len(string_conversions))
-	i := 0
-	for command, converter := range string_conversions {
+// Conversions kept in their own maps, these are added to string_conversions
+var conversion_groups = []map[string]StringConversion{
+	hash_conversions,
+	encoding_conversions,
+	case_conversions,
+	json_conversions,
+	unicode_conversions,
+	module_conversions,
+}
+
+func init() {
+	for _, group := range conversion_groups {
+		for name, conversion := range group {
+			string_conversions[name] = conversion
+		}
+	}
+}
+
+func stringCommands(searchQuery []string) []AlfredItem {
+	commands := make([]string, 0, len(string_conversions))
+	for command := range string_conversions {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	helpText := make([]AlfredItem, len(commands))
+	for i, command := range commands {
 		helpText[i] = AlfredItem{
 			UID:          command,
 			Title:        command,
-			Subtitle:     converter.Description,
+			Subtitle:     string_conversions[command].Description,
 			Arg:          []string{command + " "},
 			Autocomplete: command,
 		}
-		i++
 	}
-	return helpText
+	return filterAlfredItems(helpText, searchQuery)
+}
+
+// Separators between the stages of a pipeline
+var pipeline_separators = []string{"|", "->"}
+
+func cutPipelineSeparator(query string) (string, bool) {
+	for _, separator := range pipeline_separators {
+		if strings.HasPrefix(query, separator) {
+			return query[len(separator):], true
+		}
+	}
+	return query, false
+}
+
+// Split a query like "lower | sha256 some text" into the conversion names
+// and the string they're run on. The separators don't need spaces around
+// them, so "unbase64->upper" is two stages
+func parseStringPipeline(query string) ([]string, string) {
+	stages := []string{}
+	rest := strings.TrimLeftFunc(query, unicode.IsSpace)
+	for {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		for _, separator := range pipeline_separators {
+			index := strings.Index(rest[:end], separator)
+			if index != -1 && index < end {
+				end = index
+			}
+		}
+		stages = append(stages, rest[:end])
+		rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+
+		var more bool
+		rest, more = cutPipelineSeparator(rest)
+		if !more {
+			return stages, rest
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+}
+
+// Longer previews are cut so the subtitle stays readable
+const pipelinePreviewLength = 40
+
+func pipelinePreview(str string) string {
+	runes := []rune(singleLine(str))
+	if len(runes) > pipelinePreviewLength {
+		return string(runes[:pipelinePreviewLength-1]) + "…"
+	}
+	return string(runes)
+}
+
+// Run each stage on the output of the one before it. The subtitle previews
+// the result of every stage but the last, since that's the title
+func runStringPipeline(config *Config, stages []string, input_string string) (AlfredItem, error) {
+	previews := []string{}
+	result := input_string
+	for i, stage := range stages {
+		converter, exists := string_conversions[stage]
+		if !exists {
+			return AlfredItem{}, fmt.Errorf("Stage %d (%s) failed: unknown string subcommand", i+1, stage)
+		}
+		var err error
+		result, err = converter.run(config, result)
+		if err != nil && len(stages) == 1 {
+			return AlfredItem{}, err
+		} else if err != nil {
+			return AlfredItem{}, fmt.Errorf("Stage %d (%s) failed: %s", i+1, stage, err)
+		}
+		if i < len(stages)-1 {
+			previews = append(previews, fmt.Sprintf("%s: %s", stage, pipelinePreview(result)))
+		}
+	}
+	return alfredItemFromString(result, false).withSubtitle(strings.Join(previews, " → ")), nil
 }
 
-func stringCommand(args []string) ([]AlfredItem, error) {
+// Prefix of the first arg that previews every conversion of the string
+const stringPreviewPrefix = "?"
+
+// How useful a preview is likely to be, higher is shown first. A decode
+// only succeeds when the input really is encoded, so those come first. Hashes
+// and encodings aren't readable so they're after the other conversions, and
+// conversions that don't change the string come last
+func previewUsefulness(name string, input_string string, result string) int {
+	_, isEncoding := encoding_conversions[name]
+	_, isHash := hash_conversions[name]
+	switch {
+	case result == input_string:
+		return 0
+	case isEncoding && strings.HasPrefix(name, "un") && !strings.Contains(result, "\\x"):
+		return 3
+	case isEncoding || isHash:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Run every conversion on the string, conversions that fail are left out.
+// With a filter the conversion names are fuzzy matched against it instead of
+// being ranked by usefulness
+func stringPreviews(config *Config, filter string, input_string string) []AlfredItem {
+	names := make([]string, 0, len(string_conversions))
+	for name, conversion := range string_conversions {
+		if !conversion.NoPreview {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := []AlfredItem{}
+	usefulness := map[string]int{}
+	for _, name := range names {
+		result, err := string_conversions[name].run(config, input_string)
+		if err != nil {
+			continue
+		}
+		usefulness[name] = previewUsefulness(name, input_string, result)
+		item := alfredItemFromString(result, false).
+			withSubtitle(name).
+			withMatch(name)
+		item.UID = name
+		item.Title = singleLine(result)
+		item.Autocomplete = name + " " + input_string
+		items = append(items, item)
+	}
+
+	if filter != "" {
+		return filterAlfredItems(items, []string{filter})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return usefulness[items[i].UID] > usefulness[items[j].UID]
+	})
+	return items
+}
+
+func stringCommand(config *Config, args []string) ([]AlfredItem, error) {
 	if len(args) == 0 {
-		return stringCommands(), nil
+		return stringCommands([]string{}), nil
 	}
 
 	if len(args) == 1 {
-		return []AlfredItem{}, nil
+		return stringCommands([]string{strings.TrimPrefix(args[0], stringPreviewPrefix)}), nil
 	}
 
-	subcmd := args[0]
-	input_string := strings.Join(args[1:], " ")
-	result := "Unknown string subcommand"
+	if strings.HasPrefix(args[0], stringPreviewPrefix) {
+		filter := strings.TrimPrefix(args[0], stringPreviewPrefix)
+		return stringPreviews(config, filter, strings.Join(args[1:], " ")), nil
+	}
 
-	converter, exists := string_conversions[subcmd]
-	if exists {
-		result = converter.Convert(input_string)
+	stages, input_string := parseStringPipeline(strings.Join(args, " "))
+	if len(stages) == 1 {
+		// Partially typed subcommands filter the previews
+		if _, exists := string_conversions[stages[0]]; !exists {
+			return stringPreviews(config, stages[0], input_string), nil
+		}
 	}
 
-	resp := []AlfredItem{
-		alfredItemFromString(result, false),
+	item, err := runStringPipeline(config, stages, input_string)
+	if err != nil {
+		return []AlfredItem{}, err
 	}
-	return resp, nil
+	return []AlfredItem{item}, nil
 }
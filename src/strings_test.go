@@ -1,12 +1,13 @@
 package ralphred
 
 import (
+	"strings"
 	"testing"
 )
 
 func assertStringCommandResult(t *testing.T, input []string, expected string) {
 	t.Helper()
-	items, err := stringCommand(input)
+	items, err := stringCommand(defaultConfig(), input)
 	if err != nil {
 		t.Fatalf("Got an err: %s", err)
 	}
@@ -98,3 +99,100 @@ func TestHashes(t *testing.T) {
 		assertStringCommandResult(t, []string{"sha512", "word"}, "e1cc867e070565b17656702f48d54c483b3fb64fe4d2f0bb30b6c4ec84e4b8d51fe3cdebe2324e7dec3c82f6971d89b52a6c3beb8d5dda2b9b1a80ddc129d073")
 	})
 }
+
+func TestStringCommandKeepsWhitespace(t *testing.T) {
+	assertQueryResult := func(t *testing.T, query string, expected string) {
+		t.Helper()
+		cmd, _ := commandRegistry.Lookup("strings")
+		items, err := cmd.Run(newCommandRequest(query))
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != expected {
+			t.Fatalf("Got %s expected %s", items[0].Title, expected)
+		}
+	}
+	t.Run("Quoted", func(t *testing.T) {
+		assertQueryResult(t, `length "a  b"`, "4")
+	})
+	t.Run("Unquoted", func(t *testing.T) {
+		assertQueryResult(t, "length a  b", "4")
+	})
+}
+
+func TestParseStringPipeline(t *testing.T) {
+	cases := map[string]string{
+		"lower | sha256 Hello World": "lower,sha256:Hello World",
+		"unbase64->upper aGk=":       "unbase64,upper:aGk=",
+		"lower|upper  a | b":         "lower,upper:a | b",
+		"upper a -> b":               "upper:a -> b",
+	}
+	for query, expected := range cases {
+		t.Run(query, func(t *testing.T) {
+			stages, input := parseStringPipeline(query)
+			result := strings.Join(stages, ",") + ":" + input
+			if result != expected {
+				t.Fatalf("Got %s expected %s", result, expected)
+			}
+		})
+	}
+}
+
+func TestStringPipeline(t *testing.T) {
+	t.Run("Result", func(t *testing.T) {
+		assertStringCommandResult(t, []string{"lower", "| sha256 Hello World"}, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9")
+	})
+	t.Run("PreviewsStages", func(t *testing.T) {
+		items, err := stringCommand(defaultConfig(), []string{"unbase64", "-> upper | length aGk="})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		expected := "unbase64: hi → upper: HI"
+		if items[0].Subtitle != expected {
+			t.Fatalf("Got %s expected %s", items[0].Subtitle, expected)
+		}
+	})
+	t.Run("FailingStage", func(t *testing.T) {
+		assertStringCommandError(t, []string{"upper", "| unbase64 !!"}, "Stage 2 (unbase64) failed: Invalid base64 at character 1")
+		assertStringCommandError(t, []string{"upper", "| nope x"}, "Stage 2 (nope) failed")
+	})
+}
+
+func TestStringPreviews(t *testing.T) {
+	t.Run("AllConversions", func(t *testing.T) {
+		items, err := stringCommand(defaultConfig(), []string{"?", "aGk="})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Title != "hi" || items[0].Subtitle != "unbase64" {
+			t.Fatalf("Got %s (%s) expected hi (unbase64)", items[0].Title, items[0].Subtitle)
+		}
+		last := items[len(items)-1]
+		if last.Title != "aGk=" {
+			t.Fatalf("Got %s expected unchanged results last", last.Title)
+		}
+		for _, item := range items {
+			if item.Subtitle == "unhex" {
+				t.Fatalf("Expected failing conversions to be left out")
+			}
+		}
+	})
+	t.Run("PartialSubcommand", func(t *testing.T) {
+		items, err := stringCommand(defaultConfig(), []string{"upp", "Hello World"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if len(items) == 0 || items[0].Title != "HELLO WORLD" {
+			t.Fatalf("Got %v expected upper first", items)
+		}
+	})
+	t.Run("FilterAfterPrefix", func(t *testing.T) {
+		items, err := stringCommand(defaultConfig(), []string{"?sha256", "word"})
+		if err != nil {
+			t.Fatalf("Got an err: %s", err)
+		}
+		if items[0].Subtitle != "sha256" {
+			t.Fatalf("Got %s expected sha256", items[0].Subtitle)
+		}
+	})
+}
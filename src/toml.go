@@ -0,0 +1,379 @@
+package ralphred
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A parser for the parts of TOML a config file needs: tables, dotted keys,
+// strings, numbers, booleans, arrays and inline tables. Dates and multi-line
+// strings aren't supported
+type tomlParser struct {
+	data string
+	pos  int
+	// Tables defined with a [header], redefining one is an error
+	defined map[string]bool
+}
+
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	parser := &tomlParser{data: string(data), defined: map[string]bool{}}
+	root := map[string]interface{}{}
+	current := root
+	for {
+		parser.skipBlankLines()
+		if parser.done() {
+			return root, nil
+		}
+
+		var err error
+		if parser.peek() == '[' {
+			current, err = parser.parseHeader(root)
+		} else {
+			err = parser.parseKeyValue(current)
+		}
+		if err == nil {
+			err = parser.endOfLine()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *tomlParser) errorf(format string, v ...interface{}) error {
+	line := strings.Count(parser.data[:parser.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, v...))
+}
+
+func (parser *tomlParser) done() bool {
+	return parser.pos >= len(parser.data)
+}
+
+func (parser *tomlParser) peek() byte {
+	if parser.done() {
+		return 0
+	}
+	return parser.data[parser.pos]
+}
+
+func (parser *tomlParser) expect(char byte) error {
+	if parser.peek() != char {
+		return parser.errorf("expected %q", char)
+	}
+	parser.pos++
+	return nil
+}
+
+func (parser *tomlParser) skipSpaces() {
+	for parser.peek() == ' ' || parser.peek() == '\t' {
+		parser.pos++
+	}
+}
+
+func (parser *tomlParser) skipComment() {
+	if parser.peek() != '#' {
+		return
+	}
+	for !parser.done() && parser.peek() != '\n' {
+		parser.pos++
+	}
+}
+
+// Skip spaces, comments and new lines
+func (parser *tomlParser) skipBlankLines() {
+	for {
+		parser.skipSpaces()
+		parser.skipComment()
+		if parser.peek() == '\r' || parser.peek() == '\n' {
+			parser.pos++
+		} else {
+			return
+		}
+	}
+}
+
+func (parser *tomlParser) endOfLine() error {
+	parser.skipSpaces()
+	parser.skipComment()
+	if strings.HasPrefix(parser.data[parser.pos:], "\r\n") {
+		parser.pos += 2
+	} else if parser.peek() == '\n' {
+		parser.pos++
+	} else if !parser.done() {
+		return parser.errorf("expected a new line, got %q", parser.peek())
+	}
+	return nil
+}
+
+// Parse a [table] header and return the table it defines
+func (parser *tomlParser) parseHeader(root map[string]interface{}) (map[string]interface{}, error) {
+	if strings.HasPrefix(parser.data[parser.pos:], "[[") {
+		return nil, parser.errorf("arrays of tables aren't supported")
+	}
+	parser.pos++
+	parser.skipSpaces()
+	keys, err := parser.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	err = parser.expect(']')
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.Join(keys, ".")
+	if parser.defined[name] {
+		return nil, parser.errorf("table %s is defined twice", name)
+	}
+	parser.defined[name] = true
+	return parser.subtable(root, keys)
+}
+
+// Get the table at keys, creating any that don't exist
+func (parser *tomlParser) subtable(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for i, key := range keys {
+		existing, exists := table[key]
+		if !exists {
+			next := map[string]interface{}{}
+			table[key] = next
+			table = next
+			continue
+		}
+		next, isTable := existing.(map[string]interface{})
+		if !isTable {
+			return nil, parser.errorf("%s isn't a table", strings.Join(keys[:i+1], "."))
+		}
+		table = next
+	}
+	return table, nil
+}
+
+func (parser *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	keys, err := parser.parseKey()
+	if err != nil {
+		return err
+	}
+	parser.skipSpaces()
+	err = parser.expect('=')
+	if err != nil {
+		return err
+	}
+	parser.skipSpaces()
+	value, err := parser.parseValue()
+	if err != nil {
+		return err
+	}
+
+	table, err = parser.subtable(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := table[last]; exists {
+		return parser.errorf("%s is set twice", strings.Join(keys, "."))
+	}
+	table[last] = value
+	return nil
+}
+
+func isBareKeyChar(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9') || char == '_' || char == '-'
+}
+
+// Parse a possibly dotted key into its parts
+func (parser *tomlParser) parseKey() ([]string, error) {
+	keys := []string{}
+	for {
+		var key string
+		var err error
+		switch parser.peek() {
+		case '"':
+			key, err = parser.parseBasicString()
+		case '\'':
+			key, err = parser.parseLiteralString()
+		default:
+			start := parser.pos
+			for isBareKeyChar(parser.peek()) {
+				parser.pos++
+			}
+			if start == parser.pos {
+				return nil, parser.errorf("expected a key")
+			}
+			key = parser.data[start:parser.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+
+		parser.skipSpaces()
+		if parser.peek() != '.' {
+			return keys, nil
+		}
+		parser.pos++
+		parser.skipSpaces()
+	}
+}
+
+func (parser *tomlParser) parseValue() (interface{}, error) {
+	switch parser.peek() {
+	case '"':
+		return parser.parseBasicString()
+	case '\'':
+		return parser.parseLiteralString()
+	case '[':
+		return parser.parseArray()
+	case '{':
+		return parser.parseInlineTable()
+	}
+
+	start := parser.pos
+	for !parser.done() && !strings.ContainsRune(" \t\r\n,]}#", rune(parser.peek())) {
+		parser.pos++
+	}
+	token := parser.data[start:parser.pos]
+	switch token {
+	case "":
+		return nil, parser.errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	// Base 0 would read a leading zero as octal, TOML doesn't allow those
+	digits := strings.TrimLeft(token, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return nil, parser.errorf("invalid value %s", token)
+	}
+	if integer, err := strconv.ParseInt(token, 0, 64); err == nil {
+		return integer, nil
+	}
+	if float, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64); err == nil {
+		return float, nil
+	}
+	return nil, parser.errorf("invalid value %s", token)
+}
+
+var toml_escapes = map[byte]string{
+	'b':  "\b",
+	't':  "\t",
+	'n':  "\n",
+	'f':  "\f",
+	'r':  "\r",
+	'"':  "\"",
+	'\\': "\\",
+}
+
+func (parser *tomlParser) parseBasicString() (string, error) {
+	if strings.HasPrefix(parser.data[parser.pos:], `"""`) {
+		return "", parser.errorf("multi-line strings aren't supported")
+	}
+	parser.pos++
+	var builder strings.Builder
+	for {
+		if parser.done() || parser.peek() == '\n' {
+			return "", parser.errorf("unterminated string")
+		}
+		char := parser.peek()
+		parser.pos++
+		if char == '"' {
+			return builder.String(), nil
+		} else if char != '\\' {
+			builder.WriteByte(char)
+			continue
+		}
+
+		if parser.done() {
+			return "", parser.errorf("unterminated string")
+		}
+		escape := parser.peek()
+		parser.pos++
+		if replacement, exists := toml_escapes[escape]; exists {
+			builder.WriteString(replacement)
+			continue
+		}
+		length := map[byte]int{'u': 4, 'U': 8}[escape]
+		if length == 0 || parser.pos+length > len(parser.data) {
+			return "", parser.errorf("invalid escape \\%c", escape)
+		}
+		code, err := strconv.ParseUint(parser.data[parser.pos:parser.pos+length], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return "", parser.errorf("invalid escape \\%c%s", escape, parser.data[parser.pos:parser.pos+length])
+		}
+		builder.WriteRune(rune(code))
+		parser.pos += length
+	}
+}
+
+func (parser *tomlParser) parseLiteralString() (string, error) {
+	if strings.HasPrefix(parser.data[parser.pos:], "'''") {
+		return "", parser.errorf("multi-line strings aren't supported")
+	}
+	parser.pos++
+	start := parser.pos
+	for parser.peek() != '\'' {
+		if parser.done() || parser.peek() == '\n' {
+			return "", parser.errorf("unterminated string")
+		}
+		parser.pos++
+	}
+	parser.pos++
+	return parser.data[start : parser.pos-1], nil
+}
+
+// Arrays can span lines and have a trailing comma
+func (parser *tomlParser) parseArray() ([]interface{}, error) {
+	parser.pos++
+	values := []interface{}{}
+	for {
+		parser.skipBlankLines()
+		if parser.peek() == ']' {
+			parser.pos++
+			return values, nil
+		}
+		value, err := parser.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		parser.skipBlankLines()
+		if parser.peek() == ',' {
+			parser.pos++
+		} else if parser.peek() != ']' {
+			return nil, parser.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// Inline tables have to be on one line and can't have a trailing comma
+func (parser *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	parser.pos++
+	table := map[string]interface{}{}
+	parser.skipSpaces()
+	if parser.peek() == '}' {
+		parser.pos++
+		return table, nil
+	}
+	for {
+		parser.skipSpaces()
+		err := parser.parseKeyValue(table)
+		if err != nil {
+			return nil, err
+		}
+		parser.skipSpaces()
+		switch parser.peek() {
+		case '}':
+			parser.pos++
+			return table, nil
+		case ',':
+			parser.pos++
+		default:
+			return nil, parser.errorf("expected , or } in inline table")
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ralphred
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Values",
+			input:    "a = 'C:\\dir'\nb = \"tab\\there\\u00e9\"\nc = 1_000\nd = 0x10\ne = 1.5\nf = true\n",
+			expected: map[string]interface{}{"a": "C:\\dir", "b": "tab\thereé", "c": int64(1000), "d": int64(16), "e": 1.5, "f": true},
+		},
+		{
+			name:  "Tables",
+			input: "top = 1 # comment\n\n[a.b]\nc = 2\n[\"quoted key\"]\nd.e = 3\r\n",
+			expected: map[string]interface{}{
+				"top":        int64(1),
+				"a":          map[string]interface{}{"b": map[string]interface{}{"c": int64(2)}},
+				"quoted key": map[string]interface{}{"d": map[string]interface{}{"e": int64(3)}},
+			},
+		},
+		{
+			name:  "ArraysAndInlineTables",
+			input: "list = [\n  \"a\", # first\n  \"b\",\n]\ntable = {x = 1, y.z = []}\n",
+			expected: map[string]interface{}{
+				"list":  []interface{}{"a", "b"},
+				"table": map[string]interface{}{"x": int64(1), "y": map[string]interface{}{"z": []interface{}{}}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := parseTOML([]byte(test.input))
+			if err != nil {
+				t.Fatalf("Got an err: %s", err)
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Fatalf("Got %#v expected %#v", values, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\na = 2":    "line 2: a is set twice",
+		"[a]\n[a]":        "line 2: table a is defined twice",
+		"a = 1\n[a]":      "line 2: a isn't a table",
+		"a = 1 b = 2":     "line 1: expected a new line",
+		"a = 010":         "line 1: invalid value 010",
+		"a = 1979-05-27":  "line 1: invalid value 1979-05-27",
+		"a = \"\\q\"":     "line 1: invalid escape \\q",
+		"a = \"\\":        "line 1: unterminated string",
+		"a = '''multi'''": "line 1: multi-line strings aren't supported",
+		"[[a]]":           "line 1: arrays of tables aren't supported",
+		"a = [1 2]":       "line 1: expected , or ] in array",
+		"= 1":             "line 1: expected a key",
+		"a =":             "line 1: expected a value",
+	}
+	for input, expected := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := parseTOML([]byte(input))
+			if err == nil {
+				t.Fatalf("Expected error, but didn't get one")
+			}
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("Got error %q expected it to contain %q", err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package ralphred
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var unicode_category_names = map[string]string{
+	"Lu": "uppercase letter",
+	"Ll": "lowercase letter",
+	"Lt": "titlecase letter",
+	"Lm": "modifier letter",
+	"Lo": "other letter",
+	"Mn": "nonspacing mark",
+	"Mc": "spacing mark",
+	"Me": "enclosing mark",
+	"Nd": "decimal number",
+	"Nl": "letter number",
+	"No": "other number",
+	"Pc": "connector punctuation",
+	"Pd": "dash punctuation",
+	"Ps": "open punctuation",
+	"Pe": "close punctuation",
+	"Pi": "initial punctuation",
+	"Pf": "final punctuation",
+	"Po": "other punctuation",
+	"Sm": "math symbol",
+	"Sc": "currency symbol",
+	"Sk": "modifier symbol",
+	"So": "other symbol",
+	"Zs": "space separator",
+	"Zl": "line separator",
+	"Zp": "paragraph separator",
+	"Cc": "control",
+	"Cf": "format",
+	"Co": "private use",
+	"Cs": "surrogate",
+}
+
+// unicode.Categories has the major categories and LC too, only the ones
+// with names are general categories
+var unicode_general_categories = func() []string {
+	names := []string{}
+	for name := range unicode_category_names {
+		if _, exists := unicode.Categories[name]; exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}()
+
+func generalCategory(char rune) string {
+	for _, name := range unicode_general_categories {
+		if unicode.Is(unicode.Categories[name], char) {
+			return name
+		}
+	}
+	return "Cn"
+}
+
+// Characters that don't show up or show up as a plain space. The stdlib
+// doesn't have character names, so the ones worth pointing out are here
+var unicode_invisible_names = map[rune]string{
+	0x00A0: "no-break space",
+	0x00AD: "soft hyphen",
+	0x034F: "combining grapheme joiner",
+	0x115F: "hangul choseong filler",
+	0x1160: "hangul jungseong filler",
+	0x180E: "mongolian vowel separator",
+	0x2000: "en quad",
+	0x2001: "em quad",
+	0x2002: "en space",
+	0x2003: "em space",
+	0x2004: "three-per-em space",
+	0x2005: "four-per-em space",
+	0x2006: "six-per-em space",
+	0x2007: "figure space",
+	0x2008: "punctuation space",
+	0x2009: "thin space",
+	0x200A: "hair space",
+	0x200B: "zero width space",
+	0x200C: "zero width non-joiner",
+	0x200D: "zero width joiner",
+	0x2028: "line separator",
+	0x2029: "paragraph separator",
+	0x202F: "narrow no-break space",
+	0x205F: "medium mathematical space",
+	0x2060: "word joiner",
+	0x2061: "function application",
+	0x2062: "invisible times",
+	0x2063: "invisible separator",
+	0x2064: "invisible plus",
+	0x3000: "ideographic space",
+	0x3164: "hangul filler",
+	0xFEFF: "zero width no-break space (byte order mark)",
+	0xFFA0: "halfwidth hangul filler",
+}
+
+var unicode_bidi_control_names = map[rune]string{
+	0x061C: "arabic letter mark",
+	0x200E: "left-to-right mark",
+	0x200F: "right-to-left mark",
+	0x202A: "left-to-right embedding",
+	0x202B: "right-to-left embedding",
+	0x202C: "pop directional formatting",
+	0x202D: "left-to-right override",
+	0x202E: "right-to-left override",
+	0x2066: "left-to-right isolate",
+	0x2067: "right-to-left isolate",
+	0x2068: "first strong isolate",
+	0x2069: "pop directional isolate",
+}
+
+// Common characters that are easily mistaken for ASCII. Fullwidth forms are
+// handled separately since they're a whole block
+var unicode_confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'е': "e", 'о': "o", 'р': "p", 'с': "c", 'у': "y", 'х': "x",
+	'і': "i", 'ј': "j", 'ѕ': "s", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w", 'һ': "h",
+	'А': "A", 'В': "B", 'Е': "E", 'К': "K", 'М': "M", 'Н': "H", 'О': "O",
+	'Р': "P", 'С': "C", 'Т': "T", 'Х': "X", 'І': "I", 'Ј': "J", 'Ѕ': "S",
+	// Greek
+	'ο': "o", 'ν': "v", 'Α': "A", 'Β': "B", 'Ε': "E", 'Ζ': "Z", 'Η': "H",
+	'Ι': "I", 'Κ': "K", 'Μ': "M", 'Ν': "N", 'Ο': "O", 'Ρ': "P", 'Τ': "T",
+	'Υ': "Y", 'Χ': "X",
+	// Punctuation that gets swapped in by word processors
+	'‘': "'", '’': "'", '‚': ",", '′': "'", '“': "\"", '”': "\"", '″': "\"",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '−': "-", '⁄': "/",
+	'∕': "/", '∗': "*", 'ǀ': "|", '׃': ":", '։': ":", '˸': ":",
+}
+
+type unicodeFlag struct {
+	Kind string
+	Note string
+}
+
+// Why a character might not be what it looks like, nil when it's fine
+func flagCharacter(char rune) *unicodeFlag {
+	if name, exists := unicode_bidi_control_names[char]; exists {
+		return &unicodeFlag{"Bidi control", name}
+	}
+	if name, exists := unicode_invisible_names[char]; exists {
+		return &unicodeFlag{"Invisible", name}
+	}
+	switch {
+	case char == '\t' || char == '\n' || char == '\r':
+		return nil
+	case unicode.Is(unicode.Variation_Selector, char):
+		return &unicodeFlag{"Invisible", "variation selector"}
+	case unicode.Is(unicode.Cc, char):
+		return &unicodeFlag{"Invisible", "control character"}
+	case unicode.Is(unicode.Cf, char):
+		return &unicodeFlag{"Invisible", "format character"}
+	case char >= 0xFF01 && char <= 0xFF5E:
+		return &unicodeFlag{"Confusable", fmt.Sprintf("fullwidth %q", char-0xFEE0)}
+	}
+	if lookalike, exists := unicode_confusables[char]; exists {
+		return &unicodeFlag{"Confusable", fmt.Sprintf("looks like %q", lookalike)}
+	}
+	return nil
+}
+
+func isRegionalIndicator(char rune) bool {
+	return char >= 0x1F1E6 && char <= 0x1F1FF
+}
+
+// Ranges of Extended_Pictographic characters, the ones that zero width joiners
+// join into emoji sequences. Close to the Unicode property without needing
+// the full table
+var extended_pictographic_ranges = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x2388, 0x2388}, {0x23CF, 0x23CF},
+	{0x23E9, 0x23F3}, {0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB},
+	{0x25B6, 0x25B6}, {0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x27BF},
+	{0x2934, 0x2935}, {0x2B05, 0x2B07}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55}, {0x3030, 0x3030}, {0x303D, 0x303D}, {0x3297, 0x3297},
+	{0x3299, 0x3299}, {0x1F000, 0x1F1E5}, {0x1F200, 0x1F3FA}, {0x1F400, 0x1FAFF},
+	{0x1FC00, 0x1FFFD},
+}
+
+func isExtendedPictographic(char rune) bool {
+	for _, bounds := range extended_pictographic_ranges {
+		if char >= bounds[0] && char <= bounds[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Characters that attach to whatever is before them
+func isGraphemeExtend(char rune) bool {
+	switch {
+	case unicode.In(char, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case char == 0x200D || unicode.Is(unicode.Variation_Selector, char):
+		return true
+	case char >= 0x1F3FB && char <= 0x1F3FF:
+		// Emoji skin tone modifiers
+		return true
+	case char >= 0xE0020 && char <= 0xE007F:
+		// Tags used by subdivision flags
+		return true
+	}
+	return false
+}
+
+// Characters that attach to the one before them in a grapheme cluster.
+// afterPictographic is whether the cluster so far is a pictographic character
+// followed only by extending characters
+func extendsGrapheme(previous rune, char rune, afterPictographic bool) bool {
+	switch {
+	case previous == '\r' && char == '\n':
+		return true
+	case previous == 0x200D && afterPictographic && isExtendedPictographic(char):
+		// Zero width joiner joins emoji sequences
+		return true
+	case isGraphemeExtend(char):
+		return true
+	case char >= 0x1160 && char <= 0x11FF && previous >= 0x1100 && previous <= 0x11FF:
+		// Hangul jamo vowels and trailing consonants
+		return true
+	}
+	return false
+}
+
+// Split a string into grapheme clusters, the characters a person would
+// count. This is a simplified version of the UAX #29 rules that handles
+// combining marks, emoji sequences and flags
+func splitGraphemes(str string) []string {
+	graphemes := []string{}
+	start := 0
+	var previous rune = -1
+	pendingRegional := false
+	afterPictographic := false
+	for i, char := range str {
+		joins := false
+		if previous != -1 {
+			if isRegionalIndicator(char) && isRegionalIndicator(previous) && pendingRegional {
+				// Regional indicators pair up into flags
+				joins = true
+			} else {
+				joins = extendsGrapheme(previous, char, afterPictographic)
+			}
+		}
+		if previous != -1 && !joins {
+			graphemes = append(graphemes, str[start:i])
+			start = i
+		}
+		if isRegionalIndicator(char) {
+			pendingRegional = !(joins && isRegionalIndicator(previous))
+		} else {
+			pendingRegional = false
+		}
+		if isExtendedPictographic(char) {
+			afterPictographic = true
+		} else if !joins || !isGraphemeExtend(char) {
+			afterPictographic = false
+		}
+		previous = char
+	}
+	if start < len(str) {
+		graphemes = append(graphemes, str[start:])
+	}
+	return graphemes
+}
+
+func utf8Hex(char rune) string {
+	encoded := make([]byte, utf8.RuneLen(char))
+	utf8.EncodeRune(encoded, char)
+	parts := make([]string, len(encoded))
+	for i, b := range encoded {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, " ")
+}
+
+func utf16Hex(char rune) string {
+	units := utf16.Encode([]rune{char})
+	parts := make([]string, len(units))
+	for i, unit := range units {
+		parts[i] = fmt.Sprintf("%04X", unit)
+	}
+	return strings.Join(parts, " ")
+}
+
+// How a character is shown in a title. Whitespace is quoted so it can be
+// seen and characters that don't show up at all are left out
+func visibleCharacter(char rune, flag *unicodeFlag) string {
+	if flag != nil && flag.Kind != "Confusable" {
+		return ""
+	}
+	if unicode.IsSpace(char) || !unicode.IsPrint(char) && !unicode.IsMark(char) {
+		return fmt.Sprintf("%q", char)
+	}
+	return string(char)
+}
+
+// Inspecting a whole document would be too many items for alfred
+const maxUnicodeCodePoints = 200
+
+func codePointItem(char rune, invalid bool) AlfredItem {
+	codePoint := fmt.Sprintf("U+%04X", char)
+	if invalid {
+		return alfredItemFromString("Invalid UTF-8", false).
+			withSubtitle("Byte isn't part of a valid UTF-8 sequence")
+	}
+
+	category := generalCategory(char)
+	flag := flagCharacter(char)
+	title := codePoint
+	if visible := visibleCharacter(char, flag); visible != "" {
+		title = fmt.Sprintf("%s  %s", visible, codePoint)
+	}
+	if flag != nil {
+		title = fmt.Sprintf("%s  %s: %s", title, flag.Kind, flag.Note)
+	}
+	subtitle := fmt.Sprintf(
+		"%s %s, UTF-8 %s, UTF-16 %s",
+		category,
+		unicode_category_names[category],
+		utf8Hex(char),
+		utf16Hex(char),
+	)
+	item := alfredItemFromString(codePoint, false).withSubtitle(subtitle)
+	item.Title = title
+	return item
+}
+
+func unicodeCommand(args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return []AlfredItem{
+			alfredItemFromString("Enter text to inspect", false).withValid(false),
+		}, nil
+	}
+	input_string := strings.Join(args, " ")
+
+	flagged := 0
+	runeCount := 0
+	codePoints := []AlfredItem{}
+	for i, char := range input_string {
+		runeCount++
+		invalid := char == utf8.RuneError && !strings.HasPrefix(input_string[i:], string(utf8.RuneError))
+		if flagCharacter(char) != nil || invalid {
+			flagged++
+		}
+		if len(codePoints) < maxUnicodeCodePoints {
+			codePoints = append(codePoints, codePointItem(char, invalid))
+		}
+	}
+
+	summary := fmt.Sprintf(
+		"%s, %s, %s",
+		pluralize(len(splitGraphemes(input_string)), "grapheme"),
+		pluralize(runeCount, "rune"),
+		pluralize(len(input_string), "byte"),
+	)
+	summarySubtitle := pluralize(len(utf16.Encode([]rune(input_string))), "UTF-16 code unit")
+	if flagged > 0 {
+		summarySubtitle = fmt.Sprintf("%s, %s to look at", summarySubtitle, pluralize(flagged, "character"))
+	}
+	items := []AlfredItem{
+		alfredItemFromString(summary, false).withSubtitle(summarySubtitle).withValid(false),
+	}
+	items = append(items, codePoints...)
+	if runeCount > maxUnicodeCodePoints {
+		items = append(items, alfredItemFromString(
+			fmt.Sprintf("%d more code points", runeCount-maxUnicodeCodePoints),
+			false,
+		).withValid(false))
+	}
+	return items, nil
+}
+
+var unicode_conversions = map[string]StringConversion{
+	"bytes": {
+		Description: "Return the number of bytes in the string",
+		Convert: func(input_string string) (string, error) {
+			return fmt.Sprintf("%d", len(input_string)), nil
+		},
+	},
+	"runes": {
+		Description: "Return the number of code points in the string",
+		Convert: func(input_string string) (string, error) {
+			return fmt.Sprintf("%d", utf8.RuneCountInString(input_string)), nil
+		},
+	},
+}
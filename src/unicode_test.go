@@ -0,0 +1,85 @@
+package ralphred
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitGraphemes(t *testing.T) {
+	cases := map[string]int{
+		"héllo":     5,
+		"héllo":    5,
+		"🇨🇦🇺🇸":      2,
+		"👍🏽":        1,
+		"👩‍👩‍👧":     1,
+		"a\u200db":  2,
+		"👍\u200db":  2,
+		"a\u200d👍":  2,
+		"❤️\u200d🔥": 1,
+		"a\r\nb":    3,
+		"한국어":       3,
+		"가x":       2,
+	}
+	for input, expected := range cases {
+		t.Run(input, func(t *testing.T) {
+			result := len(splitGraphemes(input))
+			if result != expected {
+				t.Fatalf("Got %d expected %d", result, expected)
+			}
+		})
+	}
+}
+
+func TestUnicodeCounts(t *testing.T) {
+	assertStringCommandResult(t, []string{"length", "héllo"}, "5")
+	assertStringCommandResult(t, []string{"bytes", "héllo"}, "6")
+	assertStringCommandResult(t, []string{"runes", "héllo"}, "6")
+}
+
+func TestUnicodeCommand(t *testing.T) {
+	items, err := unicodeCommand([]string{"a​é"})
+	if err != nil {
+		t.Fatalf("Got an err: %s", err)
+	}
+	if items[0].Title != "3 graphemes, 3 runes, 6 bytes" {
+		t.Fatalf("Got %s", items[0].Title)
+	}
+	if !strings.HasSuffix(items[0].Subtitle, "1 character to look at") {
+		t.Fatalf("Got %s expected the zero width space to be flagged", items[0].Subtitle)
+	}
+	if items[2].Title != "U+200B  Invisible: zero width space" {
+		t.Fatalf("Got %s", items[2].Title)
+	}
+	if items[3].Subtitle != "Ll lowercase letter, UTF-8 C3 A9, UTF-16 00E9" {
+		t.Fatalf("Got %s", items[3].Subtitle)
+	}
+}
+
+func TestFlagCharacter(t *testing.T) {
+	cases := map[rune]string{
+		'‮':  "Bidi control: right-to-left override",
+		' ':  "Invisible: no-break space",
+		'а':  "Confusable: looks like \"a\"",
+		'Ａ':  "Confusable: fullwidth 'A'",
+		'️':  "Invisible: variation selector",
+		'a':  "",
+		'\n': "",
+	}
+	for char, expected := range cases {
+		flag := flagCharacter(char)
+		result := ""
+		if flag != nil {
+			result = flag.Kind + ": " + flag.Note
+		}
+		if result != expected {
+			t.Fatalf("Got %s expected %s for %U", result, expected, char)
+		}
+	}
+}
+
+func TestUnicodeSurrogatePairs(t *testing.T) {
+	items, _ := unicodeCommand([]string{"😀"})
+	if items[1].Subtitle != "So other symbol, UTF-8 F0 9F 98 80, UTF-16 D83D DE00" {
+		t.Fatalf("Got %s", items[1].Subtitle)
+	}
+}
@@ -0,0 +1,395 @@
+package ralphred
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A query parameter as it is in the URL, so untouched parameters keep their
+// encoding when the URL is rebuilt
+type urlQueryParam struct {
+	Raw   string
+	Key   string
+	Value string
+}
+
+func parseURLQuery(rawQuery string) []urlQueryParam {
+	params := []urlQueryParam{}
+	if rawQuery == "" {
+		return params
+	}
+	for _, raw := range strings.Split(rawQuery, "&") {
+		parts := strings.SplitN(raw, "=", 2)
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil {
+			key = parts[0]
+		}
+		value := ""
+		if len(parts) == 2 {
+			value, err = url.QueryUnescape(parts[1])
+			if err != nil {
+				value = parts[1]
+			}
+		}
+		params = append(params, urlQueryParam{Raw: raw, Key: key, Value: value})
+	}
+	return params
+}
+
+func newURLQueryParam(key string, value string) urlQueryParam {
+	return urlQueryParam{
+		Raw:   url.QueryEscape(key) + "=" + url.QueryEscape(value),
+		Key:   key,
+		Value: value,
+	}
+}
+
+func buildURLQuery(params []urlQueryParam) string {
+	raw := make([]string, len(params))
+	for i, param := range params {
+		raw[i] = param.Raw
+	}
+	return strings.Join(raw, "&")
+}
+
+func parseURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return nil, fmt.Errorf("Invalid URL: %s", urlErr.Err)
+	} else if err != nil {
+		return nil, fmt.Errorf("Invalid URL: %s", err)
+	}
+	return parsed, nil
+}
+
+var url_default_ports = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+// Parameters added by ad and analytics platforms to track clicks
+var url_tracking_params = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+func isTrackingParam(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), "utm_") || url_tracking_params[strings.ToLower(key)]
+}
+
+// Remove . and .. segments following RFC 3986 section 5.2.4
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	output := []string{}
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			if len(output) > 1 || len(output) == 1 && output[0] != "" {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+	return strings.Join(output, "/")
+}
+
+// Decode percent-encoding that isn't needed and use upper case for the rest
+func normalizePathEncoding(escapedPath string) string {
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		decoded, err := url.PathUnescape(segment)
+		if err == nil {
+			segments[i] = url.PathEscape(decoded)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func normalizeURL(parsed *url.URL) (*url.URL, error) {
+	normalized := *parsed
+	host := strings.ToLower(parsed.Hostname())
+	if host != "" && !strings.Contains(host, ":") {
+		var err error
+		host, err = domainToPunycode(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 addresses keep their brackets
+		host = "[" + host + "]"
+	}
+	port := parsed.Port()
+	if port != "" && port != url_default_ports[normalized.Scheme] {
+		host = host + ":" + port
+	}
+	normalized.Host = host
+
+	path := normalizePathEncoding(removeDotSegments(parsed.EscapedPath()))
+	if path == "" && normalized.Host != "" {
+		path = "/"
+	}
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return nil, err
+	}
+	normalized.Path = unescaped
+	normalized.RawPath = path
+
+	params := parseURLQuery(parsed.RawQuery)
+	sort.SliceStable(params, func(i, j int) bool { return params[i].Key < params[j].Key })
+	for i, param := range params {
+		params[i] = newURLQueryParam(param.Key, param.Value)
+	}
+	normalized.RawQuery = buildURLQuery(params)
+	normalized.ForceQuery = false
+	return &normalized, nil
+}
+
+// Make target relative to base, they need the same scheme and host
+func relativeURL(target *url.URL, base *url.URL) (string, error) {
+	if !target.IsAbs() || !base.IsAbs() {
+		return "", errors.New("Both URLs need to be absolute to make one relative")
+	}
+	if target.Scheme != base.Scheme || target.Host != base.Host {
+		return "", fmt.Errorf("%s and %s are on different hosts", target.Host, base.Host)
+	}
+
+	basePath := base.EscapedPath()
+	if basePath == "" {
+		basePath = "/"
+	}
+	baseDirs := strings.Split(basePath[:strings.LastIndex(basePath, "/")], "/")
+	targetPath := target.EscapedPath()
+	if targetPath == "" {
+		targetPath = "/"
+	}
+	targetSegments := strings.Split(targetPath, "/")
+
+	common := 0
+	for common < len(baseDirs) && common < len(targetSegments)-1 && baseDirs[common] == targetSegments[common] {
+		common++
+	}
+	relative := strings.Repeat("../", len(baseDirs)-common) + strings.Join(targetSegments[common:], "/")
+	firstSegment := strings.SplitN(relative, "/", 2)[0]
+	if relative == "" || strings.Contains(firstSegment, ":") {
+		// A colon in the first segment would be read as a scheme
+		relative = "./" + relative
+	}
+	if target.RawQuery != "" {
+		relative += "?" + target.RawQuery
+	}
+	if target.Fragment != "" {
+		relative += "#" + target.EscapedFragment()
+	}
+	return relative, nil
+}
+
+// Items for each part of the URL. The arg of each is the whole URL and
+// copying one gives just that part
+func urlPartItems(parsed *url.URL) []AlfredItem {
+	fullURL := parsed.String()
+	items := []AlfredItem{}
+	add := func(title string, value string, subtitle string) {
+		item := alfredItemFromString(title, false).
+			withSubtitle(subtitle).
+			withText(value, title)
+		item.Arg = []string{fullURL}
+		items = append(items, item)
+	}
+
+	if parsed.Scheme != "" {
+		add(parsed.Scheme, parsed.Scheme, "Scheme")
+	}
+	if parsed.User != nil {
+		add(parsed.User.Username(), parsed.User.Username(), "User")
+		if password, exists := parsed.User.Password(); exists {
+			add(password, password, "Password")
+		}
+	}
+	if host := parsed.Hostname(); host != "" {
+		subtitle := "Host"
+		if decoded, err := domainFromPunycode(host); err == nil && decoded != host {
+			subtitle = fmt.Sprintf("Host, %s", decoded)
+		}
+		add(host, host, subtitle)
+	}
+	if port := parsed.Port(); port != "" {
+		add(port, port, "Port")
+	} else if port, exists := url_default_ports[parsed.Scheme]; exists && parsed.Host != "" {
+		add(port, port, "Port, default for "+parsed.Scheme)
+	}
+	if path := parsed.EscapedPath(); path != "" && path != "/" {
+		add(parsed.Path, parsed.Path, "Path")
+		for i, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+			decoded, err := url.PathUnescape(segment)
+			if err != nil {
+				decoded = segment
+			}
+			add(decoded, decoded, fmt.Sprintf("Path segment %d", i+1))
+		}
+	}
+	for _, param := range parseURLQuery(parsed.RawQuery) {
+		add(fmt.Sprintf("%s = %s", param.Key, param.Value), param.Value, "Query parameter")
+	}
+	if parsed.Fragment != "" {
+		add(parsed.Fragment, parsed.Fragment, "Fragment")
+	}
+	return items
+}
+
+// The updated URL followed by its parts
+func updatedURLItems(updated *url.URL, subtitle string) []AlfredItem {
+	summary := alfredItemFromString(updated.String(), false).withSubtitle(subtitle)
+	summary.UID = "url"
+	return append([]AlfredItem{summary}, urlPartItems(updated)...)
+}
+
+// Each arg is key=value for add and replace, or a key for remove
+func updateURLParams(parsed *url.URL, subcommand string, args []string) (string, error) {
+	params := parseURLQuery(parsed.RawQuery)
+	changed := 0
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if subcommand != "remove" && len(parts) != 2 {
+			return "", fmt.Errorf("Expected key=value got %s", arg)
+		}
+		kept := []urlQueryParam{}
+		replaced := false
+		for _, param := range params {
+			switch {
+			case param.Key != parts[0] || subcommand == "add":
+				kept = append(kept, param)
+			case subcommand == "replace" && !replaced:
+				// The first one is replaced in place and the rest are removed
+				kept = append(kept, newURLQueryParam(parts[0], parts[1]))
+				replaced = true
+				changed++
+			default:
+				changed++
+			}
+		}
+		if subcommand == "add" || subcommand == "replace" && !replaced {
+			kept = append(kept, newURLQueryParam(parts[0], parts[1]))
+			changed++
+		}
+		params = kept
+	}
+	parsed.RawQuery = buildURLQuery(params)
+	switch subcommand {
+	case "add":
+		return fmt.Sprintf("Added %s", pluralize(changed, "parameter")), nil
+	case "replace":
+		return fmt.Sprintf("Set %s", pluralize(len(args), "parameter")), nil
+	}
+	return fmt.Sprintf("Removed %s", pluralize(changed, "parameter")), nil
+}
+
+var url_usage = []AlfredItem{
+	alfredItemFromString("url <url>", false).withSubtitle("Split a URL into its parts").withValid(false),
+	alfredItemFromString("url add|replace <url> <key=value...>", false).withSubtitle("Add query parameters or replace their values").withValid(false),
+	alfredItemFromString("url remove <url> <key...>", false).withSubtitle("Remove query parameters").withValid(false),
+	alfredItemFromString("url strip <url>", false).withSubtitle("Remove tracking parameters like utm_source and fbclid").withValid(false),
+	alfredItemFromString("url normalize <url>", false).withSubtitle("Normalize case, ports, paths, encoding and parameter order").withValid(false),
+	alfredItemFromString("url relative|absolute <url> <base>", false).withSubtitle("Make a URL relative to a base or resolve it against one").withValid(false),
+}
+
+func urlCommand(args []string) ([]AlfredItem, error) {
+	if len(args) == 0 {
+		return url_usage, nil
+	}
+
+	subcommand := args[0]
+	switch subcommand {
+	case "add", "replace", "remove", "strip", "normalize", "relative", "absolute":
+	default:
+		parsed, err := parseURL(strings.Join(args, " "))
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return urlPartItems(parsed), nil
+	}
+
+	if len(args) < 2 {
+		return filterAlfredItems(url_usage[1:], args[:1]), nil
+	}
+	parsed, err := parseURL(args[1])
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+
+	switch subcommand {
+	case "add", "replace", "remove":
+		if len(args) < 3 {
+			return []AlfredItem{}, fmt.Errorf("Give the parameters to %s after the URL", subcommand)
+		}
+		subtitle, err := updateURLParams(parsed, subcommand, args[2:])
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return updatedURLItems(parsed, subtitle), nil
+	case "strip":
+		kept := []urlQueryParam{}
+		for _, param := range parseURLQuery(parsed.RawQuery) {
+			if !isTrackingParam(param.Key) {
+				kept = append(kept, param)
+			}
+		}
+		removed := len(parseURLQuery(parsed.RawQuery)) - len(kept)
+		parsed.RawQuery = buildURLQuery(kept)
+		return updatedURLItems(parsed, fmt.Sprintf("Removed %s", pluralize(removed, "tracking parameter"))), nil
+	case "normalize":
+		normalized, err := normalizeURL(parsed)
+		if err != nil {
+			return []AlfredItem{}, err
+		}
+		return updatedURLItems(normalized, "Normalized"), nil
+	}
+
+	if len(args) < 3 {
+		return []AlfredItem{}, fmt.Errorf("Give the base URL after the URL to make %s", subcommand)
+	}
+	base, err := parseURL(args[2])
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	if subcommand == "absolute" {
+		if !base.IsAbs() {
+			return []AlfredItem{}, errors.New("The base URL needs to be absolute")
+		}
+		return updatedURLItems(base.ResolveReference(parsed), fmt.Sprintf("Resolved against %s", base)), nil
+	}
+	relative, err := relativeURL(parsed, base)
+	if err != nil {
+		return []AlfredItem{}, err
+	}
+	item := alfredItemFromString(relative, false).withSubtitle(fmt.Sprintf("Relative to %s", base))
+	item.UID = "url"
+	return []AlfredItem{item}, nil
+}
@@ -2,18 +2,21 @@ package ralphred
 
 import (
 	"encoding/hex"
+	"fmt"
 	"hash"
 	"regexp"
 	"strings"
 )
 
-var numberWithUnitRegex = regexp.MustCompile("^(?P<number>-?[0-9.]+)(?P<remaining>[^0-9.]+)")
+// Whitespace is allowed between the number and unit so quoted args like
+// "2 days" are split the same as 2days
+var numberWithUnitRegex = regexp.MustCompile(`^(?P<number>-?[0-9.]+)\s*(?P<remaining>[^0-9.\s][^0-9.]*)`)
 
 func splitUnitFromNumber(args []string) []string {
 	new_args := []string{}
 	for _, str := range args {
 		match := numberWithUnitRegex.FindStringSubmatch(str)
-		if match == nil {
+		if match == nil || isBaseLiteral(str) {
 			new_args = append(new_args, str)
 		} else {
 			number := match[numberWithUnitRegex.SubexpIndex("number")]
@@ -30,11 +33,15 @@ func hashString(hasher hash.Hash, toHash string) string {
 	return hex.EncodeToString(hashBytes)
 }
 
-func queryMatches(test_string string, terms []string) bool {
-	for _, term := range terms {
-		if !strings.Contains(test_string, term) {
-			return false
+// Format a count with the noun made plural when needed, like "3 keys"
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	for _, ending := range []string{"s", "x", "ch", "sh"} {
+		if strings.HasSuffix(noun, ending) {
+			return fmt.Sprintf("%d %ses", count, noun)
 		}
 	}
-	return true
+	return fmt.Sprintf("%d %ss", count, noun)
 }
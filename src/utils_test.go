@@ -2,9 +2,21 @@ package ralphred
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
+// Run a command handler on a query, stdin is only given to the command when
+// it isn't empty
+func runCommandQuery(t *testing.T, handler func(CommandRequest) ([]AlfredItem, error), query string, stdin string) ([]AlfredItem, error) {
+	t.Helper()
+	req := newCommandRequest(query)
+	if stdin != "" {
+		req.Stdin = strings.NewReader(stdin)
+	}
+	return handler(req)
+}
+
 func TestSplitNumbersAndText(t *testing.T) {
 	assertResult := func(input []string, expected []string) {
 		result := splitUnitFromNumber(input)
@@ -33,35 +45,13 @@ func TestSplitNumbersAndText(t *testing.T) {
 	t.Run("FloatNumberNoUnit", func(t *testing.T) {
 		assertResult([]string{"1.2", "a"}, []string{"1.2", "a"})
 	})
-}
-
-func TestQueryMatches(t *testing.T) {
-	assertMatch := func(str string, terms []string, matches bool) {
-		result := queryMatches(str, terms)
-		if result != matches {
-			if matches {
-				t.Fatal("Query didn't match when it should")
-			} else {
-				t.Fatalf("Query matched when it shouldn't")
-			}
-		}
-	}
-	t.Run("EmptyString", func(t *testing.T) {
-		assertMatch("", []string{"test"}, false)
-	})
-	t.Run("NoSearchString", func(t *testing.T) {
-		assertMatch("test", []string{}, true)
-	})
-	t.Run("OneSearchStringMatches", func(t *testing.T) {
-		assertMatch("test", []string{"te"}, true)
-	})
-	t.Run("OneSearchStringNoMatch", func(t *testing.T) {
-		assertMatch("test", []string{"oue"}, false)
+	t.Run("QuotedWithSpace", func(t *testing.T) {
+		assertResult([]string{"2 days"}, []string{"2", "days"})
 	})
-	t.Run("MultiSearchStringNoMatch", func(t *testing.T) {
-		assertMatch("test", []string{"te", "st"}, true)
+	t.Run("HexLiteral", func(t *testing.T) {
+		assertResult([]string{"0xff", "0b101", "-0o17"}, []string{"0xff", "0b101", "-0o17"})
 	})
-	t.Run("MultiSearchStringOneMatch", func(t *testing.T) {
-		assertMatch("test", []string{"te", "oue"}, false)
+	t.Run("ZeroWithUnit", func(t *testing.T) {
+		assertResult([]string{"0b", "0bit"}, []string{"0", "b", "0", "bit"})
 	})
 }
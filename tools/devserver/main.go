@@ -2,57 +2,206 @@ package main
 
 import (
 	"bytes"
-	"errors"
+	_ "embed"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	ralphred "github.com/kdeal/ralphred/src"
 )
 
-func run_cmd(w http.ResponseWriter, req *http.Request) {
-	log.Printf("Recieved a request. [%s] %s", req.Method, req.URL)
+//go:embed index.html
+var indexHTML []byte
+
+// Commands write to the global logger, so runs are serialized to capture the
+// logs for each one
+var runLock sync.Mutex
+
+type runResult struct {
+	Command    string          `json:"command"`
+	Query      string          `json:"query"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Logs       string          `json:"logs"`
+	DurationMs float64         `json:"duration_ms"`
+	BuildError string          `json:"build_error,omitempty"`
+}
+
+func runCommand(cmd string, query string) runResult {
+	runLock.Lock()
+	defer runLock.Unlock()
+
+	var logs bytes.Buffer
+	log.SetOutput(io.MultiWriter(&logs, os.Stderr))
+	defer log.SetOutput(os.Stderr)
+
+	var output bytes.Buffer
+	start := time.Now()
+	err := ralphred.RunWithOptions(cmd, query, ralphred.RunOptions{Output: &output})
+	duration := time.Since(start)
+
+	result := runResult{
+		Command:    cmd,
+		Query:      query,
+		Response:   bytes.TrimSpace(output.Bytes()),
+		Logs:       logs.String(),
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		BuildError: watcher.buildError(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Response = nil
+	}
+	return result
+}
 
+func parseRunForm(w http.ResponseWriter, req *http.Request) (string, string, bool) {
 	err := req.ParseForm()
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Failed to parse form. Error: %s", err)
 		log.Printf("Failed to parse form. Error: %s", err)
-		return
+		return "", "", false
 	}
 
-	log.Printf("Form Data: %s", req.Form)
-	formDate := req.Form
-	cmdStr, cmd_set := formDate["command"]
-	query, query_set := formDate["query"]
-
+	cmdStr, cmd_set := req.Form["command"]
+	query, query_set := req.Form["query"]
 	if !(cmd_set && query_set) {
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprint(w, "Missing query or command")
 		log.Printf("Missing query or command. command=%s, query=%s", cmdStr, query)
-		return
+		return "", "", false
 	}
+	return cmdStr[0], query[0], true
+}
 
-	cmd := exec.Command("go", "run", ".", "--command", cmdStr[0], "--query", query[0])
-	var errbuf bytes.Buffer
-	cmd.Stderr = &errbuf
-	stdout, err := cmd.Output()
-	stderr := errbuf.Bytes()
+// Serves the playground, or the raw alfred json when a command and query are
+// given so it can still be used with curl
+func index(w http.ResponseWriter, req *http.Request) {
+	log.Printf("Recieved a request. [%s] %s", req.Method, req.URL)
 
-	if err == nil {
-		w.Write(stdout)
-		log.Printf("Command Succeeded. StdError: \n%s", stderr)
-	} else if errors.Is(err, err.(*exec.ExitError)) {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(stderr)
-		log.Printf("Command failed. Error: %s. StdError: %s", err, stderr)
-	} else {
+	if req.FormValue("command") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+		return
+	}
+
+	cmd, query, ok := parseRunForm(w, req)
+	if !ok {
+		return
+	}
+	result := runCommand(cmd, query)
+	if result.Error != "" {
 		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, "Command failed. Error: %s", err)
-		log.Printf("Command failed. Error: %s", err)
+		fmt.Fprintf(w, "Command failed. Error: %s", result.Error)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result.Response)
+}
+
+func runAPI(w http.ResponseWriter, req *http.Request) {
+	cmd, query, ok := parseRunForm(w, req)
+	if !ok {
+		return
+	}
+	result := runCommand(cmd, query)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// sourceWatcher rebuilds the devserver when a go file changes and replaces
+// the running process with the new binary. If the build fails the current
+// code keeps being served and the error is shown in the playground
+type sourceWatcher struct {
+	root     string
+	lastSeen time.Time
+
+	lock    sync.Mutex
+	lastErr string
+}
+
+var watcher = &sourceWatcher{}
+
+func (s *sourceWatcher) buildError() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastErr
+}
+
+func (s *sourceWatcher) latestModTime() time.Time {
+	latest := time.Time{}
+	filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") && path != s.root {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !(strings.HasSuffix(path, ".go") || strings.HasSuffix(path, ".html")) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest
+}
+
+func (s *sourceWatcher) rebuild() {
+	binary := filepath.Join(os.TempDir(), "ralphred-devserver")
+	build := exec.Command("go", "build", "-o", binary, "./tools/devserver")
+	build.Dir = s.root
+	output, err := build.CombinedOutput()
+	if err != nil {
+		s.lock.Lock()
+		s.lastErr = fmt.Sprintf("%s\n%s", err, output)
+		s.lock.Unlock()
+		log.Printf("Rebuild failed. Error: %s\n%s", err, output)
+		return
+	}
+
+	log.Println("Rebuilt devserver, restarting")
+	err = syscall.Exec(binary, os.Args, os.Environ())
+	if err != nil {
+		log.Printf("Failed to restart. Error: %s", err)
+	}
+}
+
+func (s *sourceWatcher) watch(interval time.Duration) {
+	s.lastSeen = s.latestModTime()
+	for range time.Tick(interval) {
+		latest := s.latestModTime()
+		if latest.After(s.lastSeen) {
+			s.lastSeen = latest
+			s.rebuild()
+		}
 	}
 }
 
 func main() {
-	http.HandleFunc("/", run_cmd)
-	http.ListenAndServe(":8080", nil)
+	addr := flag.String("addr", ":8080", "Address to listen on")
+	root := flag.String("root", ".", "Root of the ralphred repo to watch for changes")
+	flag.Parse()
+
+	watcher.root = *root
+	go watcher.watch(time.Second)
+
+	http.HandleFunc("/", index)
+	http.HandleFunc("/api/run", runAPI)
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
 }